@@ -0,0 +1,44 @@
+package gongo
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Accepted is a value of Accept, Accept-Encoding or Accept-Language header
+// with its quality.
+type Accepted struct {
+	Value string
+	Q     float64
+}
+
+// ParseAccept returns lower cased values of header in header order. Values
+// without a q parameter have quality 1, other parameters are ignored.
+func ParseAccept(header string) []Accepted {
+	var accepted []Accepted
+
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+
+		a := Accepted{
+			Value: value,
+			Q:     1,
+		}
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					a.Q = q
+				}
+			}
+		}
+
+		accepted = append(accepted, a)
+	}
+
+	return accepted
+}
@@ -0,0 +1,25 @@
+package gongo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAccept(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []Accepted
+	}{
+		{"", nil},
+		{"gzip", []Accepted{{"gzip", 1}}},
+		{"text/html, application/json;q=0.9", []Accepted{{"text/html", 1}, {"application/json", 0.9}}},
+		{"en-US;q=0.8 , sl ; q = 0.5,", []Accepted{{"en-us", 0.8}, {"sl", 0.5}}},
+		{"text/html;level=1;q=0", []Accepted{{"text/html", 0}}},
+		{"br;q=invalid", []Accepted{{"br", 1}}},
+	}
+	for _, test := range tests {
+		if got := ParseAccept(test.header); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.header, got, test.want)
+		}
+	}
+}
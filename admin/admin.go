@@ -10,6 +10,12 @@ import (
 	"github.com/qor/roles"
 )
 
+// ResourceConfigurer is implemented by Resourcers that customize admin
+// resources of their models, for example with scopes and actions.
+type ResourceConfigurer interface {
+	ConfigureResource(model interface{}, res *admin.Resource)
+}
+
 type Admin struct {
 	qor *admin.Admin
 
@@ -22,14 +28,21 @@ func New(prefix string) *Admin {
 	}
 }
 
-func (a *Admin) Configure(app gongo.App) error {
-	DB := app["DB"].(*gorm.DB)
+func (a *Admin) Dependencies() []string {
+	return []string{"DB"}
+}
+
+func (a *Admin) Configure(app *gongo.App) error {
+	var DB *gorm.DB
+	if err := app.Lookup("DB", &DB); err != nil {
+		return err
+	}
 
 	a.qor = admin.New(&qor.Config{DB: DB})
 	a.qor.SetAuth(&QorAuth{})
 
-	for group, itf := range app {
-		if resourcer, ok := itf.(gongo.Resourcer); ok {
+	for _, group := range app.Names() {
+		if resourcer, ok := app.Get(group).(gongo.Resourcer); ok {
 			models := resourcer.Resources()
 
 			// TODO: generating permission names should be part of authorization
@@ -47,7 +60,7 @@ func (a *Admin) Configure(app gongo.App) error {
 
 			a.qor.AddMenu(&admin.Menu{Name: group, Permission: roles.Allow(roles.Read, readPermissions...)})
 			for i, model := range models {
-				a.qor.AddResource(model, &admin.Config{
+				res := a.qor.AddResource(model, &admin.Config{
 					Menu: []string{group},
 					Permission: roles.Allow(
 						roles.Read, readPermissions[i],
@@ -59,6 +72,9 @@ func (a *Admin) Configure(app gongo.App) error {
 						roles.Delete, deletePermissions[i],
 					),
 				})
+				if resourceConfigurer, ok := resourcer.(ResourceConfigurer); ok {
+					resourceConfigurer.ConfigureResource(model, res)
+				}
 			}
 		}
 	}
@@ -66,6 +82,16 @@ func (a *Admin) Configure(app gongo.App) error {
 	return nil
 }
 
+func (a *Admin) Prefix() string {
+	return a.prefix
+}
+
+// CSRFSameOrigin allows admin forms, which do not include a csrf token, for
+// same origin requests.
+func (a *Admin) CSRFSameOrigin() []string {
+	return []string{gongo.PathPrefix(a.prefix)}
+}
+
 func (a *Admin) ServeMux() http.Handler {
 	return a.qor.NewServeMux(a.prefix)
 }
@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/matematik7/gongo/csrf"
+	"github.com/matematik7/gongo/internal/apptest"
+)
+
+type Note struct {
+	gorm.Model
+	Text string
+}
+
+type notes struct{}
+
+func (notes) Resources() []interface{} {
+	return []interface{}{&Note{}}
+}
+
+func TestCSRFSameOrigin(t *testing.T) {
+	DB, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Close()
+	DB.AutoMigrate(&Note{})
+
+	c := csrf.New()
+	a := New("/admin")
+
+	app := apptest.New(nil)
+	app.Register("DB", DB)
+	app.Register("CSRF", c)
+	app.Register("Admin", a)
+	app.Register("Notes", notes{})
+	if err := app.Configure(); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := c.Middleware(a.ServeMux())
+
+	tests := []struct {
+		origin    string
+		forbidden bool
+	}{
+		{"http://example.com", false},
+		{"http://evil.com", true},
+		{"", true},
+	}
+	for _, test := range tests {
+		form := url.Values{"QorResource.Text": {"note"}}
+		req := httptest.NewRequest("POST", "http://example.com/admin/notes", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if forbidden := w.Code == http.StatusForbidden; forbidden != test.forbidden {
+			t.Errorf("origin %q: got status %d, want forbidden %v", test.origin, w.Code, test.forbidden)
+		}
+	}
+}
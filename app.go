@@ -1,28 +1,188 @@
 package gongo
 
 import (
+	"reflect"
+	"strings"
+	"time"
+
 	"github.com/pkg/errors"
 )
 
 type Configurer interface {
-	Configure(app App) error
+	Configure(app *App) error
+}
+
+// PostConfigurer is implemented by components that finish configuration
+// after every component is configured, for example with resources added by
+// their dependents. PostConfigure is called in the same order as Configure.
+type PostConfigurer interface {
+	PostConfigure(app *App) error
 }
 
 type Resourcer interface {
 	Resources() []interface{}
 }
 
-// TODO: change this to slice so init order is determined, get name with reflection
-type App map[string]interface{}
+// Depender is implemented by components that have to be configured after
+// other components, named as they were registered in the App.
+type Depender interface {
+	Dependencies() []string
+}
+
+type App struct {
+	// StopTimeout limits how long Run waits for components to stop.
+	StopTimeout time.Duration
+
+	names      []string
+	components map[string]interface{}
+
+	configuring string
+	configured  bool
+}
+
+func New() *App {
+	return &App{
+		StopTimeout: DefaultStopTimeout,
+		components:  make(map[string]interface{}),
+	}
+}
+
+// Register adds component under name. Components are configured in
+// registration order, unless they depend on components registered later.
+func (app *App) Register(name string, component interface{}) {
+	if _, ok := app.components[name]; !ok {
+		app.names = append(app.names, name)
+	}
+	app.components[name] = component
+}
+
+func (app *App) Get(name string) interface{} {
+	return app.components[name]
+}
+
+// Lookup stores component registered as name into target, which must be a
+// non-nil pointer to a variable of the component type or an interface it
+// implements.
+func (app *App) Lookup(name string, target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return errors.Errorf("lookup target for %s must be a non-nil pointer, got %T", name, target)
+	}
+	targetType := value.Type().Elem()
+
+	requiredBy := "app"
+	if app.configuring != "" {
+		requiredBy = "component " + app.configuring
+	}
+
+	component, ok := app.components[name]
+	if !ok || component == nil {
+		return errors.Errorf("%s requires %s of type %s, found none", requiredBy, name, targetType)
+	}
+
+	componentValue := reflect.ValueOf(component)
+	if !componentValue.Type().AssignableTo(targetType) {
+		return errors.Errorf("%s requires %s of type %s, found %T", requiredBy, name, targetType, component)
+	}
+
+	value.Elem().Set(componentValue)
+
+	return nil
+}
 
-func (app App) Configure() error {
-	for name, itf := range app {
-		if configurer, ok := itf.(Configurer); ok {
-			if err := configurer.Configure(app); err != nil {
+// Names returns names of all registered components in registration order.
+func (app *App) Names() []string {
+	names := make([]string, len(app.names))
+	copy(names, app.names)
+	return names
+}
+
+// Order returns names of all registered components sorted so that every
+// component comes after its dependencies.
+func (app *App) Order() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(app.names))
+	order := make([]string, 0, len(app.names))
+	path := []string{}
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					cycle := append(path[i:], name)
+					return errors.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+				}
+			}
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+
+		if depender, ok := app.components[name].(Depender); ok {
+			for _, dependency := range depender.Dependencies() {
+				if _, ok := app.components[dependency]; !ok {
+					return errors.Errorf("%s depends on %s, which is not registered", name, dependency)
+				}
+				if err := visit(dependency); err != nil {
+					return err
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, name)
+
+		return nil
+	}
+
+	for _, name := range app.names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+func (app *App) Configure() error {
+	order, err := app.Order()
+	if err != nil {
+		return errors.Wrap(err, "could not determine configure order")
+	}
+
+	for _, name := range order {
+		if configurer, ok := app.components[name].(Configurer); ok {
+			app.configuring = name
+			err := configurer.Configure(app)
+			app.configuring = ""
+			if err != nil {
 				return errors.Wrapf(err, "could not configure %s", name)
 			}
 		}
 	}
 
+	for _, name := range order {
+		if postConfigurer, ok := app.components[name].(PostConfigurer); ok {
+			app.configuring = name
+			err := postConfigurer.PostConfigure(app)
+			app.configuring = ""
+			if err != nil {
+				return errors.Wrapf(err, "could not configure %s", name)
+			}
+		}
+	}
+
+	app.configured = true
+
 	return nil
 }
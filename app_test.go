@@ -0,0 +1,175 @@
+package gongo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type component struct {
+	name         string
+	dependencies []string
+	configured   *[]string
+}
+
+func (c *component) Dependencies() []string {
+	return c.dependencies
+}
+
+func (c *component) Configure(app *App) error {
+	*c.configured = append(*c.configured, c.name)
+	return nil
+}
+
+func (c *component) PostConfigure(app *App) error {
+	*c.configured = append(*c.configured, "post "+c.name)
+	return nil
+}
+
+func newTestApp(dependencies map[string][]string, names ...string) (*App, *[]string) {
+	configured := &[]string{}
+	app := New()
+	for _, name := range names {
+		app.Register(name, &component{
+			name:         name,
+			dependencies: dependencies[name],
+			configured:   configured,
+		})
+	}
+	return app, configured
+}
+
+func TestOrder(t *testing.T) {
+	app, configured := newTestApp(map[string][]string{
+		"Server": {"Render", "Logging"},
+		"Render": {"Logging"},
+	}, "Server", "Render", "DB", "Logging")
+
+	want := []string{"Logging", "Render", "Server", "DB"}
+	order, err := app.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("Order: got %v, want %v", order, want)
+	}
+
+	if err := app.Configure(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range order {
+		want = append(want, "post "+name)
+	}
+	if !reflect.DeepEqual(*configured, want) {
+		t.Errorf("Configure: got %v, want %v", *configured, want)
+	}
+}
+
+func TestOrderErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		dependencies map[string][]string
+		want         string
+	}{
+		{"cycle", map[string][]string{"A": {"B"}, "B": {"C"}, "C": {"A"}}, "dependency cycle: A -> B -> C -> A"},
+		{"self", map[string][]string{"A": {"A"}}, "dependency cycle: A -> A"},
+		{"missing", map[string][]string{"B": {"Missing"}}, "B depends on Missing, which is not registered"},
+	}
+	for _, test := range tests {
+		app, configured := newTestApp(test.dependencies, "A", "B", "C")
+
+		if _, err := app.Order(); err == nil || err.Error() != test.want {
+			t.Errorf("%s: got %v, want %s", test.name, err, test.want)
+		}
+
+		err := app.Configure()
+		if err == nil || !strings.HasSuffix(err.Error(), test.want) {
+			t.Errorf("%s: Configure got %v", test.name, err)
+		}
+		if len(*configured) != 0 {
+			t.Errorf("%s: configured %v", test.name, *configured)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	app := New()
+	first, second := &component{name: "first"}, &component{name: "second"}
+	app.Register("B", first)
+	app.Register("A", &component{})
+	app.Register("B", second)
+
+	if names := app.Names(); !reflect.DeepEqual(names, []string{"B", "A"}) {
+		t.Errorf("Names: got %v", names)
+	}
+	if got := app.Get("B"); got != second {
+		t.Errorf("Get: got %v, want replaced component", got)
+	}
+	if got := app.Get("Missing"); got != nil {
+		t.Errorf("Get missing: got %v", got)
+	}
+
+	app.Names()[0] = "changed"
+	if names := app.Names(); names[0] != "B" {
+		t.Errorf("Names is not a copy: %v", names)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	app := New()
+	c := &component{name: "c"}
+	app.Register("Component", c)
+	app.Register("Nil", nil)
+
+	var got *component
+	if err := app.Lookup("Component", &got); err != nil || got != c {
+		t.Errorf("pointer: got %v, %v", got, err)
+	}
+
+	var depender Depender
+	if err := app.Lookup("Component", &depender); err != nil || depender != c {
+		t.Errorf("interface: got %v, %v", depender, err)
+	}
+
+	tests := []struct {
+		name   string
+		target interface{}
+		want   string
+	}{
+		{"Component", component{}, "lookup target for Component must be a non-nil pointer, got gongo.component"},
+		{"Component", (*Starter)(nil), "lookup target for Component must be a non-nil pointer, got *gongo.Starter"},
+		{"Component", new(Starter), "app requires Component of type gongo.Starter, found *gongo.component"},
+		{"Component", new(string), "app requires Component of type string, found *gongo.component"},
+		{"Missing", new(*component), "app requires Missing of type *gongo.component, found none"},
+		{"Nil", new(*component), "app requires Nil of type *gongo.component, found none"},
+	}
+	for _, test := range tests {
+		if err := app.Lookup(test.name, test.target); err == nil || err.Error() != test.want {
+			t.Errorf("%s %T: got %v, want %s", test.name, test.target, err, test.want)
+		}
+	}
+}
+
+type lookupComponent struct {
+	target interface{}
+	err    error
+}
+
+func (c *lookupComponent) Configure(app *App) error {
+	c.err = app.Lookup("Missing", c.target)
+	return nil
+}
+
+func TestLookupWhileConfiguring(t *testing.T) {
+	c := &lookupComponent{target: new(*component)}
+	app := New()
+	app.Register("Server", c)
+	if err := app.Configure(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "component Server requires Missing of type *gongo.component, found none"
+	if c.err == nil || c.err.Error() != want {
+		t.Errorf("got %v, want %s", c.err, want)
+	}
+}
@@ -0,0 +1,338 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/compress"
+	"github.com/matematik7/gongo/logging"
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+)
+
+// ManifestName is the file in source that maps asset names to fingerprinted
+// names. If it exists in production, assets are not hashed on startup.
+const ManifestName = "manifest.json"
+
+const hashLength = 10
+
+const (
+	immutableCacheControl  = "public, max-age=31536000, immutable"
+	revalidateCacheControl = "no-cache"
+)
+
+// encodings of precompressed variants in order of preference
+var encodings = []struct {
+	name      string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+type Assets struct {
+	isProd bool
+	prefix string
+	source http.FileSystem
+
+	logging *logging.Logging
+
+	mutex    sync.RWMutex
+	manifest map[string]string
+	hashed   map[string]string
+}
+
+// New serves files from source under urlPrefix. In development hashes are
+// computed on every use, so changed files get new urls without restart.
+func New(isProd bool, urlPrefix string, source http.FileSystem) *Assets {
+	return &Assets{
+		isProd: isProd,
+		prefix: urlPrefix,
+		source: source,
+	}
+}
+
+func (a *Assets) Dependencies() []string {
+	return []string{"Logging", "Render"}
+}
+
+func (a *Assets) Configure(app *gongo.App) error {
+	if err := app.Lookup("Logging", &a.logging); err != nil {
+		return err
+	}
+	var r *render.Render
+	if err := app.Lookup("Render", &r); err != nil {
+		return err
+	}
+
+	if a.isProd {
+		manifest, err := a.loadManifest()
+		if err != nil {
+			return err
+		}
+		if manifest == nil {
+			manifest, err = a.Manifest()
+			if err != nil {
+				return err
+			}
+		}
+		a.setManifest(manifest)
+	}
+
+	r.AddContextFunc(func(req *http.Request, ctx render.Context) {
+		ctx["static"] = func(name string) string {
+			url, err := a.URL(name)
+			if err != nil {
+				a.logging.WithRequest(req).WithError(err).Errorf("could not get url for asset %s", name)
+			}
+			return url
+		}
+	})
+
+	return nil
+}
+
+func (a *Assets) setManifest(manifest map[string]string) {
+	hashed := make(map[string]string, len(manifest))
+	for name, hashedName := range manifest {
+		hashed[hashedName] = name
+	}
+
+	a.mutex.Lock()
+	a.manifest = manifest
+	a.hashed = hashed
+	a.mutex.Unlock()
+}
+
+// URL returns fingerprinted url of asset name. If the asset can not be
+// hashed, url without fingerprint is returned together with the error.
+func (a *Assets) URL(name string) (string, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	prefix := gongo.PathPrefix(a.prefix) + "/"
+
+	if a.isProd {
+		a.mutex.RLock()
+		hashedName, ok := a.manifest[name]
+		a.mutex.RUnlock()
+		if ok {
+			return prefix + hashedName, nil
+		}
+		return prefix + name, errors.Errorf("asset %s not found in manifest", name)
+	}
+
+	hashedName, err := a.hashName(name)
+	if err != nil {
+		return prefix + name, err
+	}
+	return prefix + hashedName, nil
+}
+
+// Manifest hashes all assets in source and returns map of asset names to
+// fingerprinted names.
+func (a *Assets) Manifest() (map[string]string, error) {
+	manifest := make(map[string]string)
+	err := render.WalkTemplates(a.source, "", func(name string, info os.FileInfo) error {
+		if name == ManifestName || isCompressed(name) {
+			return nil
+		}
+		hashedName, err := a.hashName(name)
+		if err != nil {
+			return err
+		}
+		manifest[name] = hashedName
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// WriteManifest writes manifest of all assets as json, to be saved as
+// ManifestName in source at build time.
+func (a *Assets) WriteManifest(w io.Writer) error {
+	manifest, err := a.Manifest()
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return errors.Wrap(err, "could not write manifest")
+	}
+
+	return nil
+}
+
+func (a *Assets) loadManifest() (map[string]string, error) {
+	f, err := a.source.Open("/" + ManifestName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "could not open manifest")
+	}
+	defer f.Close()
+
+	manifest := make(map[string]string)
+	if err := json.NewDecoder(f).Decode(&manifest); err != nil {
+		return nil, errors.Wrap(err, "could not decode manifest")
+	}
+
+	return manifest, nil
+}
+
+func (a *Assets) hashName(name string) (string, error) {
+	f, err := a.source.Open("/" + name)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not open asset %s", name)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", errors.Wrapf(err, "could not hash asset %s", name)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))[:hashLength]
+
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + sum + ext, nil
+}
+
+// unhashName returns asset name for fingerprinted name and whether name was
+// fingerprinted with the current content of the asset.
+func (a *Assets) unhashName(name string) (string, bool) {
+	if a.isProd {
+		a.mutex.RLock()
+		original, ok := a.hashed[name]
+		a.mutex.RUnlock()
+		if ok {
+			return original, true
+		}
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	i := strings.LastIndex(base, ".")
+	if i < 0 || !isHash(base[i+1:]) {
+		return name, false
+	}
+	original := base[:i] + ext
+
+	if !a.isProd {
+		hashedName, err := a.hashName(original)
+		return original, err == nil && hashedName == name
+	}
+
+	return original, false
+}
+
+func (a *Assets) Prefix() string {
+	return a.prefix
+}
+
+func (a *Assets) ServeMux() http.Handler {
+	router := chi.NewRouter()
+
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+chi.URLParam(r, "*")), "/")
+		name, immutable := a.unhashName(name)
+		if name == ManifestName || isCompressed(name) {
+			http.NotFound(w, r)
+			return
+		}
+
+		f, encoding, err := a.open(name, r.Header.Get("Accept-Encoding"))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				a.logging.WithRequest(r).WithError(err).Errorf("could not open asset %s", name)
+			}
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		header := w.Header()
+		if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+			header.Set("Content-Type", ctype)
+		}
+		header.Add("Vary", "Accept-Encoding")
+		if encoding != "" {
+			header.Set("Content-Encoding", encoding)
+		}
+		if immutable {
+			header.Set("Cache-Control", immutableCacheControl)
+		} else {
+			header.Set("Cache-Control", revalidateCacheControl)
+		}
+
+		http.ServeContent(w, r, name, info.ModTime(), f)
+	}
+
+	router.Get("/*", serve)
+	router.Head("/*", serve)
+
+	return router
+}
+
+// open returns the precompressed variant of asset name the client prefers,
+// otherwise the asset itself.
+func (a *Assets) open(name, acceptEncoding string) (http.File, string, error) {
+	variants := make(map[string]http.File)
+	available := []string{}
+	for _, encoding := range encodings {
+		f, err := a.source.Open("/" + name + encoding.extension)
+		if err == nil {
+			variants[encoding.name] = f
+			available = append(available, encoding.name)
+		}
+	}
+
+	encoding := compress.Negotiate(acceptEncoding, available...)
+	for variant, f := range variants {
+		if variant != encoding {
+			f.Close()
+		}
+	}
+	if encoding != "" {
+		return variants[encoding], encoding, nil
+	}
+
+	f, err := a.source.Open("/" + name)
+	return f, "", err
+}
+
+func isCompressed(name string) bool {
+	for _, encoding := range encodings {
+		if strings.HasSuffix(name, encoding.extension) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHash(s string) bool {
+	if len(s) != hashLength {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
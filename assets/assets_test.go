@@ -0,0 +1,81 @@
+package assets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matematik7/gongo/internal/apptest"
+	"github.com/matematik7/gongo/server"
+)
+
+func TestServeMounted(t *testing.T) {
+	for _, isProd := range []bool{false, true} {
+		source := fstest.MapFS{
+			"css/app.css":    {Data: []byte("body { color: red; }")},
+			"css/app.css.gz": {Data: []byte("gzipped")},
+		}
+		a := New(isProd, "/static", http.FS(source))
+		s := server.New("127.0.0.1:0")
+
+		app := apptest.New(nil)
+		app.Register("Assets", a)
+		app.Register("Server", s)
+		if err := app.Configure(); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		hashed, err := a.URL("css/app.css")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hashed == "/static/css/app.css" {
+			t.Errorf("url %s is not hashed", hashed)
+		}
+
+		tests := []struct {
+			path           string
+			acceptEncoding string
+			status         int
+			body           string
+			cacheControl   string
+		}{
+			{hashed, "", http.StatusOK, "body { color: red; }", immutableCacheControl},
+			{hashed, "br, gzip", http.StatusOK, "gzipped", immutableCacheControl},
+			{hashed, "gzip;q=0", http.StatusOK, "body { color: red; }", immutableCacheControl},
+			{"/static/css/app.css", "", http.StatusOK, "body { color: red; }", revalidateCacheControl},
+			{"/static/css/app.0123456789.css", "", http.StatusOK, "body { color: red; }", revalidateCacheControl},
+			{"/static/css/app.css.gz", "", http.StatusNotFound, "", ""},
+			{"/static/css/missing.css", "", http.StatusNotFound, "", ""},
+		}
+		for _, test := range tests {
+			req := httptest.NewRequest("GET", test.path, nil)
+			if test.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", test.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Errorf("prod %v, GET %s: got status %d, want %d", isProd, test.path, w.Code, test.status)
+				continue
+			}
+			if test.status != http.StatusOK {
+				continue
+			}
+			if body := w.Body.String(); body != test.body {
+				t.Errorf("prod %v, GET %s: got body %q, want %q", isProd, test.path, body, test.body)
+			}
+			if cacheControl := w.Header().Get("Cache-Control"); cacheControl != test.cacheControl {
+				t.Errorf("prod %v, GET %s: got Cache-Control %q, want %q", isProd, test.path, cacheControl, test.cacheControl)
+			}
+		}
+
+		s.Stop(context.Background())
+	}
+}
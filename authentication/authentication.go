@@ -1,20 +1,30 @@
 package authentication
 
 import (
+	"embed"
+	"io/fs"
 	"net/http"
 
 	"github.com/go-chi/chi"
 	"github.com/gorilla/sessions"
 	"github.com/matematik7/gongo"
 	"github.com/matematik7/gongo/authorization"
+	"github.com/matematik7/gongo/logging"
 	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
+//go:embed templates
+var defaultTemplates embed.FS
+
 type Authentication struct {
 	authorization *authorization.Authorization
 	render        *render.Render
+	log           *logrus.Logger
 
-	appURL string
+	appURL    string
+	providers map[string]ProviderConfig
 }
 
 func New(appURL string) *Authentication {
@@ -25,6 +35,10 @@ func New(appURL string) *Authentication {
 	return auth
 }
 
+func (auth *Authentication) Prefix() string {
+	return gongo.PathPrefix(auth.appURL)
+}
+
 func (auth *Authentication) ServeMux() http.Handler {
 	router := chi.NewRouter()
 
@@ -33,10 +47,45 @@ func (auth *Authentication) ServeMux() http.Handler {
 	return router
 }
 
-func (auth *Authentication) Configure(app gongo.App) error {
-	auth.authorization = app["Authorization"].(*authorization.Authorization)
-	auth.render = app["Render"].(*render.Render)
-	auth.ConfigureGoth(app["Store"].(sessions.Store), auth.appURL)
+func (auth *Authentication) Dependencies() []string {
+	return []string{"Config", "Logging", "Authorization", "Render", "Store"}
+}
+
+func (auth *Authentication) ConfigKey() string {
+	return "goth"
+}
+
+func (auth *Authentication) ConfigStruct() interface{} {
+	return &auth.providers
+}
+
+func (auth *Authentication) Configure(app *gongo.App) error {
+	// routes like /{provider} would catch every page when mounted at root
+	if auth.Prefix() == "" {
+		return errors.Errorf("authentication url %s must have a path, like https://host/auth", auth.appURL)
+	}
+
+	if err := app.Lookup("Authorization", &auth.authorization); err != nil {
+		return err
+	}
+	if err := app.Lookup("Render", &auth.render); err != nil {
+		return err
+	}
+	var l *logging.Logging
+	if err := app.Lookup("Logging", &l); err != nil {
+		return err
+	}
+	auth.log = l.Logger()
+
+	templates, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return errors.Wrap(err, "could not load default templates")
+	}
+	auth.render.AddDefaultTemplates(http.FS(templates))
+	var store sessions.Store
+	if err := app.Lookup("Store", &store); err != nil {
+		return err
+	}
 
-	return nil
+	return auth.ConfigureGoth(store, auth.appURL)
 }
@@ -5,8 +5,8 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
-	"log"
 	"net/http"
+	"sort"
 
 	"github.com/go-chi/chi"
 	"github.com/gorilla/sessions"
@@ -48,8 +48,9 @@ import (
 	"github.com/markbates/goth/providers/xero"
 	"github.com/markbates/goth/providers/yahoo"
 	"github.com/markbates/goth/providers/yammer"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/render"
 	"github.com/pkg/errors"
-	"github.com/spf13/viper"
 )
 
 type key int
@@ -58,142 +59,150 @@ const gothStateKey key = iota
 
 const stateTokenLength = 256 / 8
 
-type providerConstructor func(key, secret, callbackUrl string) goth.Provider
+type providerConstructor func(c ProviderConfig, callbackUrl string) goth.Provider
 
 var availableProviders = map[string]providerConstructor{
-	"twitter": func(key, secret, callbackUrl string) goth.Provider {
-		return twitter.New(key, secret, callbackUrl)
+	"twitter": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return twitter.New(c.Key, c.Secret, callbackUrl)
 	},
-	"facebook": func(key, secret, callbackUrl string) goth.Provider {
-		return facebook.New(key, secret, callbackUrl)
+	"facebook": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return facebook.New(c.Key, c.Secret, callbackUrl)
 	},
-	"fitbit": func(key, secret, callbackUrl string) goth.Provider {
-		return fitbit.New(key, secret, callbackUrl)
+	"fitbit": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return fitbit.New(c.Key, c.Secret, callbackUrl)
 	},
-	"gplus": func(key, secret, callbackUrl string) goth.Provider {
-		return gplus.New(key, secret, callbackUrl)
+	"gplus": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return gplus.New(c.Key, c.Secret, callbackUrl)
 	},
-	"github": func(key, secret, callbackUrl string) goth.Provider {
-		return github.New(key, secret, callbackUrl)
+	"github": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return github.New(c.Key, c.Secret, callbackUrl)
 	},
-	"spotify": func(key, secret, callbackUrl string) goth.Provider {
-		return spotify.New(key, secret, callbackUrl)
+	"spotify": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return spotify.New(c.Key, c.Secret, callbackUrl)
 	},
-	"linkedin": func(key, secret, callbackUrl string) goth.Provider {
-		return linkedin.New(key, secret, callbackUrl)
+	"linkedin": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return linkedin.New(c.Key, c.Secret, callbackUrl)
 	},
-	"lastfm": func(key, secret, callbackUrl string) goth.Provider {
-		return lastfm.New(key, secret, callbackUrl)
+	"lastfm": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return lastfm.New(c.Key, c.Secret, callbackUrl)
 	},
-	"twitch": func(key, secret, callbackUrl string) goth.Provider {
-		return twitch.New(key, secret, callbackUrl)
+	"twitch": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return twitch.New(c.Key, c.Secret, callbackUrl)
 	},
-	"dropbox": func(key, secret, callbackUrl string) goth.Provider {
-		return dropbox.New(key, secret, callbackUrl)
+	"dropbox": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return dropbox.New(c.Key, c.Secret, callbackUrl)
 	},
-	"digitalocean": func(key, secret, callbackUrl string) goth.Provider {
-		return digitalocean.New(key, secret, callbackUrl)
+	"digitalocean": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return digitalocean.New(c.Key, c.Secret, callbackUrl)
 	},
-	"bitbucket": func(key, secret, callbackUrl string) goth.Provider {
-		return bitbucket.New(key, secret, callbackUrl)
+	"bitbucket": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return bitbucket.New(c.Key, c.Secret, callbackUrl)
 	},
-	"instagram": func(key, secret, callbackUrl string) goth.Provider {
-		return instagram.New(key, secret, callbackUrl)
+	"instagram": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return instagram.New(c.Key, c.Secret, callbackUrl)
 	},
-	"intercom": func(key, secret, callbackUrl string) goth.Provider {
-		return intercom.New(key, secret, callbackUrl)
+	"intercom": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return intercom.New(c.Key, c.Secret, callbackUrl)
 	},
-	"box": func(key, secret, callbackUrl string) goth.Provider {
-		return box.New(key, secret, callbackUrl)
+	"box": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return box.New(c.Key, c.Secret, callbackUrl)
 	},
-	"salesforce": func(key, secret, callbackUrl string) goth.Provider {
-		return salesforce.New(key, secret, callbackUrl)
+	"salesforce": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return salesforce.New(c.Key, c.Secret, callbackUrl)
 	},
-	"amazon": func(key, secret, callbackUrl string) goth.Provider {
-		return amazon.New(key, secret, callbackUrl)
+	"amazon": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return amazon.New(c.Key, c.Secret, callbackUrl)
 	},
-	"yammer": func(key, secret, callbackUrl string) goth.Provider {
-		return yammer.New(key, secret, callbackUrl)
+	"yammer": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return yammer.New(c.Key, c.Secret, callbackUrl)
 	},
-	"onedrive": func(key, secret, callbackUrl string) goth.Provider {
-		return onedrive.New(key, secret, callbackUrl)
+	"onedrive": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return onedrive.New(c.Key, c.Secret, callbackUrl)
 	},
-	"battlenet": func(key, secret, callbackUrl string) goth.Provider {
-		return battlenet.New(key, secret, callbackUrl)
+	"battlenet": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return battlenet.New(c.Key, c.Secret, callbackUrl)
 	},
-	"yahoo": func(key, secret, callbackUrl string) goth.Provider {
-		return yahoo.New(key, secret, callbackUrl)
+	"yahoo": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return yahoo.New(c.Key, c.Secret, callbackUrl)
 	},
-	"slack": func(key, secret, callbackUrl string) goth.Provider {
-		return slack.New(key, secret, callbackUrl)
+	"slack": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return slack.New(c.Key, c.Secret, callbackUrl)
 	},
-	"stripe": func(key, secret, callbackUrl string) goth.Provider {
-		return stripe.New(key, secret, callbackUrl)
+	"stripe": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return stripe.New(c.Key, c.Secret, callbackUrl)
 	},
-	"wepay": func(key, secret, callbackUrl string) goth.Provider {
-		return wepay.New(key, secret, callbackUrl, "view_user")
+	"wepay": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return wepay.New(c.Key, c.Secret, callbackUrl, "view_user")
 	},
-	"paypal": func(key, secret, callbackUrl string) goth.Provider {
-		return paypal.New(key, secret, callbackUrl)
+	"paypal": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return paypal.New(c.Key, c.Secret, callbackUrl)
 	},
-	"steam": func(key, secret, callbackUrl string) goth.Provider {
-		return steam.New(key, callbackUrl)
+	"steam": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return steam.New(c.Key, callbackUrl)
 	},
-	"heroku": func(key, secret, callbackUrl string) goth.Provider {
-		return heroku.New(key, secret, callbackUrl)
+	"heroku": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return heroku.New(c.Key, c.Secret, callbackUrl)
 	},
-	"uber": func(key, secret, callbackUrl string) goth.Provider {
-		return uber.New(key, secret, callbackUrl)
+	"uber": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return uber.New(c.Key, c.Secret, callbackUrl)
 	},
-	"soundcloud": func(key, secret, callbackUrl string) goth.Provider {
-		return soundcloud.New(key, secret, callbackUrl)
+	"soundcloud": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return soundcloud.New(c.Key, c.Secret, callbackUrl)
 	},
-	"gitlab": func(key, secret, callbackUrl string) goth.Provider {
-		return gitlab.New(key, secret, callbackUrl)
+	"gitlab": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return gitlab.New(c.Key, c.Secret, callbackUrl)
 	},
-	"dailymotion": func(key, secret, callbackUrl string) goth.Provider {
-		return dailymotion.New(key, secret, callbackUrl, "email")
+	"dailymotion": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return dailymotion.New(c.Key, c.Secret, callbackUrl, "email")
 	},
-	"deezer": func(key, secret, callbackUrl string) goth.Provider {
-		return deezer.New(key, secret, callbackUrl, "email")
+	"deezer": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return deezer.New(c.Key, c.Secret, callbackUrl, "email")
 	},
-	"discord": func(key, secret, callbackUrl string) goth.Provider {
-		return discord.New(key, secret, callbackUrl, discord.ScopeIdentify, discord.ScopeEmail)
+	"discord": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return discord.New(c.Key, c.Secret, callbackUrl, discord.ScopeIdentify, discord.ScopeEmail)
 	},
-	"meetup": func(key, secret, callbackUrl string) goth.Provider {
-		return meetup.New(key, secret, callbackUrl)
+	"meetup": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return meetup.New(c.Key, c.Secret, callbackUrl)
 	},
-	"auth0": func(key, secret, callbackUrl string) goth.Provider {
-		return auth0.New(key, secret, callbackUrl, viper.GetString("goth.auth0.domain"))
+	"auth0": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return auth0.New(c.Key, c.Secret, callbackUrl, c.Domain)
 	},
-	"xero": func(key, secret, callbackUrl string) goth.Provider {
-		return xero.New(key, secret, callbackUrl)
+	"xero": func(c ProviderConfig, callbackUrl string) goth.Provider {
+		return xero.New(c.Key, c.Secret, callbackUrl)
 	},
 }
 
-func (auth *Authentication) ConfigureGoth(store sessions.Store, appURL string) {
+// ProviderConfig holds credentials of one goth provider, configured under
+// goth.<provider>.
+type ProviderConfig struct {
+	Key    string `valid:"required"`
+	Secret string `secret:"true"`
+	Domain string
+}
+
+func (auth *Authentication) ConfigureGoth(store sessions.Store, appURL string) error {
 	gothic.Store = store
 
 	providers := []goth.Provider{}
-	for name, constructor := range availableProviders {
-		viperKey := fmt.Sprintf("goth.%s.key", name)
-		if !viper.IsSet(viperKey) {
-			continue
+	for name, config := range auth.providers {
+		constructor, ok := availableProviders[name]
+		if !ok {
+			return errors.Errorf("unknown goth provider %s", name)
+		}
+		if name == "auth0" && config.Domain == "" {
+			return errors.New("goth.auth0.domain is required")
 		}
 
-		viperSecret := fmt.Sprintf("goth.%s.secret", name)
 		callbackURL := fmt.Sprintf("%s/%s/callback/", appURL, name)
 
-		log.Printf("Auto configured goth for: %s", name)
+		auth.log.Infof("Auto configured goth for: %s", name)
 
-		providers = append(providers, constructor(
-			viper.GetString(viperKey),
-			viper.GetString(viperSecret),
-			callbackURL,
-		))
+		providers = append(providers, constructor(config, callbackURL))
 	}
 
 	goth.UseProviders(providers...)
+
+	return nil
 }
 
 func (auth *Authentication) ConfigureGothRoutes(router chi.Router) {
@@ -205,13 +214,12 @@ func (auth *Authentication) ConfigureGothRoutes(router chi.Router) {
 	}
 
 	router.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		return auth.render.Handler(func(w http.ResponseWriter, r *http.Request) error {
 			state := r.URL.Query().Get("state")
 			if len(state) == 0 {
 				randomState, err := getRandomString(stateTokenLength)
 				if err != nil {
-					auth.render.Error(w, r, errors.Wrap(err, "could not generate random state"))
-					return
+					return errors.Wrap(err, "could not generate random state")
 				}
 
 				state = randomState
@@ -219,51 +227,64 @@ func (auth *Authentication) ConfigureGothRoutes(router chi.Router) {
 
 			ctx := context.WithValue(r.Context(), gothStateKey, state)
 			next.ServeHTTP(w, r.WithContext(ctx))
+			return nil
+		})
+	})
+
+	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		providers := make([]string, 0, len(auth.providers))
+		for name := range auth.providers {
+			providers = append(providers, name)
+		}
+		sort.Strings(providers)
+
+		auth.render.Template(w, r, "login.html", render.Context{
+			"prefix":    gongo.PathPrefix(auth.appURL),
+			"providers": providers,
 		})
 	})
 
 	// TODO: redirect back on login or logout
 	router.Route("/{provider}", func(router chi.Router) {
-		router.Get("/", func(w http.ResponseWriter, r *http.Request) {
-			if gothUser, err := gothic.CompleteUserAuth(w, r); err == nil {
-				auth.loginGoth(w, r, gothUser)
-			} else {
+		router.Get("/", auth.render.Handler(func(w http.ResponseWriter, r *http.Request) error {
+			gothUser, err := gothic.CompleteUserAuth(w, r)
+			if err != nil {
 				gothic.BeginAuthHandler(w, r)
+				return nil
 			}
-		})
+			return auth.loginGoth(w, r, gothUser)
+		}))
 
-		router.Get("/callback", func(w http.ResponseWriter, r *http.Request) {
+		router.Get("/callback", auth.render.Handler(func(w http.ResponseWriter, r *http.Request) error {
 			gothUser, err := gothic.CompleteUserAuth(w, r)
 			if err != nil {
-				auth.render.Error(w, r, err)
-				return
+				return err
 			}
-			auth.loginGoth(w, r, gothUser)
-		})
+			return auth.loginGoth(w, r, gothUser)
+		}))
 
-		router.Get("/logout", func(w http.ResponseWriter, r *http.Request) {
+		router.Get("/logout", auth.render.Handler(func(w http.ResponseWriter, r *http.Request) error {
 			// TODO: general /auth/logout that auto determines provider
 			gothic.Logout(w, r)
-			err := auth.authorization.Logout(w, r)
-			if err != nil {
-				auth.render.Error(w, r, err)
-				return
+			if err := auth.authorization.Logout(w, r); err != nil {
+				return err
 			}
 			http.Redirect(w, r, "/", http.StatusFound)
-		})
+			return nil
+		}))
 	})
 }
 
-func (auth *Authentication) loginGoth(w http.ResponseWriter, r *http.Request, gothUser goth.User) {
+func (auth *Authentication) loginGoth(w http.ResponseWriter, r *http.Request, gothUser goth.User) error {
 	id := fmt.Sprintf("goth:%s:%s", chi.URLParam(r, "provider"), gothUser.UserID)
 
 	err := auth.authorization.Login(w, r, id, gothUser.Name, gothUser.Email, gothUser.AvatarURL)
 	if err != nil {
-		auth.render.Error(w, r, err)
-		return
+		return err
 	}
 
 	http.Redirect(w, r, "/", http.StatusFound)
+	return nil
 }
 
 func getRandomString(n int) (string, error) {
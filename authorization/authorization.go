@@ -2,12 +2,15 @@ package authorization
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/jinzhu/gorm"
 	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/i18n"
+	"github.com/matematik7/gongo/logging"
 	"github.com/matematik7/gongo/render"
 	"github.com/pkg/errors"
 	"github.com/qor/roles"
@@ -31,10 +34,20 @@ func New() *Authorization {
 	}
 }
 
-func (auth *Authorization) Configure(app gongo.App) error {
-	auth.db = app["DB"].(*gorm.DB)
-	auth.store = app["Store"].(sessions.Store)
-	auth.render = app["Render"].(*render.Render)
+func (auth *Authorization) Dependencies() []string {
+	return []string{"DB", "Store", "Render"}
+}
+
+func (auth *Authorization) Configure(app *gongo.App) error {
+	if err := app.Lookup("DB", &auth.db); err != nil {
+		return err
+	}
+	if err := app.Lookup("Store", &auth.store); err != nil {
+		return err
+	}
+	if err := app.Lookup("Render", &auth.render); err != nil {
+		return err
+	}
 
 	auth.render.AddContextFunc(func(r *http.Request, ctx render.Context) {
 		if r.Context().Value("user") != nil {
@@ -42,8 +55,8 @@ func (auth *Authorization) Configure(app gongo.App) error {
 		}
 	})
 
-	for _, itf := range app {
-		if resourcer, ok := itf.(gongo.Resourcer); ok {
+	for _, name := range app.Names() {
+		if resourcer, ok := app.Get(name).(gongo.Resourcer); ok {
 			models := resourcer.Resources()
 			for _, model := range models {
 				name := auth.db.NewScope(model).TableName()
@@ -85,6 +98,14 @@ func (auth Authorization) LoggerFields(ctx context.Context) map[string]interface
 	return nil
 }
 
+// CacheVary caches pages per user.
+func (auth Authorization) CacheVary(r *http.Request) string {
+	if user, ok := r.Context().Value("user").(User); ok {
+		return fmt.Sprint(user.ID)
+	}
+	return ""
+}
+
 func (auth *Authorization) loadFromDb() error {
 	permissions := []*Permission{}
 	if err := auth.db.Find(&permissions).Error; err != nil {
@@ -173,6 +194,7 @@ func (auth *Authorization) Middleware(next http.Handler) http.Handler {
 			} else {
 				ctx := context.WithValue(r.Context(), "user", user)
 				r = r.WithContext(ctx)
+				logging.AddFields(ctx, auth.LoggerFields(ctx))
 			}
 		}
 
@@ -220,7 +242,9 @@ func (auth *Authorization) Login(w http.ResponseWriter, r *http.Request, id, nam
 
 	if !userID.User.Active {
 		tx.Rollback()
-		return errors.Errorf("User %s is not active, please contact administrator.", userID.User.Name)
+		return render.ForbiddenError{
+			Message: i18n.T(r.Context(), "User %s is not active, please contact administrator.", userID.User.Name),
+		}
 	}
 
 	userID.User.Name = name
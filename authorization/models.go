@@ -17,6 +17,7 @@ type User struct {
 	Name        string `valid:"required"`
 	Email       string
 	AvatarURL   string
+	Locale      string
 	LastLogin   time.Time
 	Active      bool
 	Permissions []Permission `gorm:"many2many:user_permission"`
@@ -39,6 +40,10 @@ func (u User) DisplayName() string {
 	return u.Name
 }
 
+func (u User) GetLocale() string {
+	return u.Locale
+}
+
 func (u User) HasPermissions(permissions ...string) bool {
 	// TODO: optimize
 	for _, requiredPerm := range permissions {
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a cached response. ETag is computed once, when the response is
+// stored, and is empty for responses other than 200 OK.
+type Entry struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	ETag    string
+	Expires time.Time
+}
+
+// Backend stores cached responses. Keys start with the request path
+// followed by ?, so pages can be invalidated by path prefix.
+type Backend interface {
+	Get(key string) (*Entry, bool)
+	Set(key string, entry *Entry)
+	DeletePrefix(prefix string)
+}
+
+type lruItem struct {
+	key   string
+	entry *Entry
+}
+
+// LRU is in-memory Backend that keeps at most size entries, evicting least
+// recently used ones.
+type LRU struct {
+	size int
+
+	mutex sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+}
+
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:  size,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+func (l *LRU) Get(key string) (*Entry, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	element, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := element.Value.(*lruItem)
+	if time.Now().After(item.entry.Expires) {
+		l.remove(element)
+		return nil, false
+	}
+
+	l.order.MoveToFront(element)
+	return item.entry, true
+}
+
+func (l *LRU) Set(key string, entry *Entry) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if element, ok := l.items[key]; ok {
+		element.Value.(*lruItem).entry = entry
+		l.order.MoveToFront(element)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&lruItem{key: key, entry: entry})
+
+	for l.order.Len() > l.size {
+		l.remove(l.order.Back())
+	}
+}
+
+func (l *LRU) DeletePrefix(prefix string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for key, element := range l.items {
+		if strings.HasPrefix(key, prefix) {
+			l.remove(element)
+		}
+	}
+}
+
+func (l *LRU) remove(element *list.Element) {
+	delete(l.items, element.Value.(*lruItem).key)
+	l.order.Remove(element)
+}
@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/logging"
+	"github.com/matematik7/gongo/render"
+)
+
+// Varier is implemented by components that render different pages for the
+// same path, for example per user or per locale.
+type Varier interface {
+	CacheVary(r *http.Request) string
+}
+
+// Skipper is implemented by components that add per request content to
+// pages, such as csrf tokens or csp nonces, so those pages are not cached.
+// Body is the response of the request.
+type Skipper interface {
+	CacheSkip(r *http.Request, body []byte) bool
+}
+
+type Cache struct {
+	backend Backend
+
+	db       *gorm.DB
+	logging  *logging.Logging
+	variers  []Varier
+	skippers []Skipper
+
+	mutex       sync.RWMutex
+	invalidates map[string][]string
+}
+
+func New(backend Backend) *Cache {
+	return &Cache{
+		backend:     backend,
+		invalidates: make(map[string][]string),
+	}
+}
+
+func (c *Cache) Dependencies() []string {
+	return []string{"DB", "Logging"}
+}
+
+func (c *Cache) Configure(app *gongo.App) error {
+	if err := app.Lookup("DB", &c.db); err != nil {
+		return err
+	}
+	if err := app.Lookup("Logging", &c.logging); err != nil {
+		return err
+	}
+
+	for _, name := range app.Names() {
+		if varier, ok := app.Get(name).(Varier); ok {
+			c.variers = append(c.variers, varier)
+		}
+		if skipper, ok := app.Get(name).(Skipper); ok {
+			c.skippers = append(c.skippers, skipper)
+		}
+	}
+
+	callback := c.db.Callback()
+	callback.Create().After("gorm:create").Register("cache:invalidate", c.invalidateScope)
+	callback.Update().After("gorm:update").Register("cache:invalidate", c.invalidateScope)
+	callback.Delete().After("gorm:delete").Register("cache:invalidate", c.invalidateScope)
+
+	return nil
+}
+
+// InvalidateOn removes pages under path prefixes from the cache whenever
+// model is created, updated or deleted through gorm.
+func (c *Cache) InvalidateOn(model interface{}, prefixes ...string) {
+	table := c.db.NewScope(model).TableName()
+
+	c.mutex.Lock()
+	c.invalidates[table] = append(c.invalidates[table], prefixes...)
+	c.mutex.Unlock()
+}
+
+// Invalidate removes pages under path prefixes from the cache, /post
+// removes /post and /post/1, but not /posts.
+func (c *Cache) Invalidate(prefixes ...string) {
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		// keys are the path followed by ?query
+		c.backend.DeletePrefix(prefix + "?")
+		c.backend.DeletePrefix(prefix + "/")
+	}
+}
+
+func (c *Cache) invalidateScope(scope *gorm.Scope) {
+	if scope.HasError() {
+		return
+	}
+
+	c.mutex.RLock()
+	prefixes := c.invalidates[scope.TableName()]
+	c.mutex.RUnlock()
+
+	c.Invalidate(prefixes...)
+}
+
+func (c *Cache) key(r *http.Request) string {
+	// negotiated responses, like render.Negotiate, depend on Accept
+	parts := []string{r.URL.Path + "?" + r.URL.RawQuery, r.Header.Get("Accept")}
+	for _, varier := range c.variers {
+		parts = append(parts, varier.CacheVary(r))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// Pages caches successful GET responses of next for ttl, per Accept header
+// and Varier values. Responses that set cookies, also in middleware around
+// the cache, and responses skipped by a Skipper are not cached.
+func (c *Cache) Pages(ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := c.key(r)
+			if entry, ok := c.backend.Get(key); ok {
+				c.serve(w, r, entry)
+				return
+			}
+
+			rec := &recorder{
+				ResponseWriter: w,
+				header:         make(http.Header),
+			}
+			next.ServeHTTP(rec, r)
+
+			entry := rec.entry()
+			if entry.Status == http.StatusOK && r.Method == http.MethodGet && c.cacheable(w, r, rec) {
+				entry.Expires = time.Now().Add(ttl)
+				c.backend.Set(key, entry)
+			}
+
+			c.serve(w, r, entry)
+		})
+	}
+}
+
+// cacheable reports if response recorded by rec can be stored, w is the
+// response of middleware around the cache.
+func (c *Cache) cacheable(w http.ResponseWriter, r *http.Request, rec *recorder) bool {
+	if rec.header.Get("Set-Cookie") != "" || w.Header().Get("Set-Cookie") != "" {
+		return false
+	}
+	cacheControl := rec.header.Get("Cache-Control")
+	if strings.Contains(cacheControl, "no-store") || strings.Contains(cacheControl, "private") {
+		return false
+	}
+
+	for _, skipper := range c.skippers {
+		if skipper.CacheSkip(r, rec.body.Bytes()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Cache) serve(w http.ResponseWriter, r *http.Request, entry *Entry) {
+	// values are copied, so handlers wrapping the cache can not change
+	// the cached entry
+	header := w.Header()
+	for name, values := range entry.Header {
+		header[name] = append([]string(nil), values...)
+	}
+	header.Add("Vary", "Accept")
+
+	if entry.Status == http.StatusOK {
+		header.Set("ETag", entry.ETag)
+		if !render.NoneMatch(r, entry.ETag) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.WriteHeader(entry.Status)
+	if r.Method != http.MethodHead {
+		if _, err := w.Write(entry.Body); err != nil {
+			c.logging.WithRequest(r).WithError(err).Debug("could not write cached response")
+		}
+	}
+}
+
+// recorder buffers response, so it can be stored in the cache.
+type recorder struct {
+	http.ResponseWriter
+
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *recorder) Write(data []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(data)
+}
+
+func (rec *recorder) entry() *Entry {
+	entry := &Entry{
+		Status: rec.status,
+		Header: rec.header,
+		Body:   rec.body.Bytes(),
+	}
+	if entry.Status == 0 {
+		entry.Status = http.StatusOK
+	}
+	if entry.Status == http.StatusOK {
+		entry.ETag = rec.header.Get("ETag")
+		if entry.ETag == "" {
+			entry.ETag = render.ETag(entry.Body)
+		}
+	}
+	return entry
+}
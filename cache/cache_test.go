@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/logging"
+)
+
+func TestPages(t *testing.T) {
+	DB, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Close()
+
+	c := New(NewLRU(10))
+
+	app := gongo.New()
+	app.Register("DB", DB)
+	app.Register("Logging", logging.New(false))
+	app.Register("Cache", c)
+	if err := app.Configure(); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	handler := c.Pages(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("page"))
+	}))
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/page", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || first.Body.String() != "page" || etag == "" {
+		t.Fatalf("first: got status %d, body %q and etag %q", first.Code, first.Body.String(), etag)
+	}
+
+	// changing headers of a response must not change the cached entry
+	first.Header()["Content-Type"][0] = "changed"
+
+	second := get("")
+	if second.Body.String() != "page" || second.Header().Get("ETag") != etag {
+		t.Errorf("second: got body %q and etag %q", second.Body.String(), second.Header().Get("ETag"))
+	}
+	if contentType := second.Header().Get("Content-Type"); contentType != "text/plain" {
+		t.Errorf("second: got Content-Type %q", contentType)
+	}
+
+	if notModified := get(etag); notModified.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: got status %d, want %d", notModified.Code, http.StatusNotModified)
+	}
+
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+
+	c.Invalidate("/page")
+	get("")
+	if calls != 2 {
+		t.Errorf("handler called %d times after invalidate, want 2", calls)
+	}
+}
+
+type skipper struct{}
+
+func (skipper) CacheSkip(r *http.Request, body []byte) bool {
+	return bytes.Contains(body, []byte("token"))
+}
+
+func newCache(t *testing.T) *Cache {
+	DB, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		DB.Close()
+	})
+
+	c := New(NewLRU(10))
+
+	app := gongo.New()
+	app.Register("DB", DB)
+	app.Register("Logging", logging.New(false))
+	app.Register("Cache", c)
+	app.Register("Skipper", skipper{})
+	if err := app.Configure(); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// counter serves path and accept header of the request and counts calls
+// per path.
+type counter map[string]int
+
+func (c counter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c[r.URL.Path]++
+	w.Write([]byte(r.URL.Path + " " + r.Header.Get("Accept")))
+}
+
+func get(handler http.Handler, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestPagesVaryAccept(t *testing.T) {
+	calls := counter{}
+	handler := newCache(t).Pages(time.Minute)(calls)
+
+	for _, accept := range []string{"text/html", "application/json", "text/html"} {
+		w := get(handler, "/page", accept)
+		if body := w.Body.String(); body != "/page "+accept {
+			t.Errorf("%s: got %q", accept, body)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("%s: got Vary %q", accept, vary)
+		}
+	}
+
+	if calls["/page"] != 2 {
+		t.Errorf("handler called %d times, want 2", calls["/page"])
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	c := newCache(t)
+	calls := counter{}
+	handler := c.Pages(time.Minute)(calls)
+
+	paths := []string{"/post", "/post/1", "/posts", "/postcard"}
+	for _, path := range paths {
+		get(handler, path, "")
+	}
+
+	c.Invalidate("/post/")
+
+	for _, path := range paths {
+		get(handler, path, "")
+	}
+
+	want := counter{"/post": 2, "/post/1": 2, "/posts": 1, "/postcard": 1}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+}
+
+func TestPagesNotCached(t *testing.T) {
+	c := newCache(t)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+	}{
+		{
+			name: "cookie",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+			}),
+		},
+		{
+			name: "skipped",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("csrf token"))
+			}),
+		},
+		{
+			name: "no-store",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", "no-store")
+			}),
+		},
+	}
+	for i, test := range tests {
+		calls := 0
+		handler := c.Pages(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			test.handler.ServeHTTP(w, r)
+		}))
+
+		path := fmt.Sprintf("/page/%d", i)
+		get(handler, path, "")
+		get(handler, path, "")
+
+		if calls != 2 {
+			t.Errorf("%s: handler called %d times, want 2", test.name, calls)
+		}
+	}
+}
+
+func TestPagesOuterCookie(t *testing.T) {
+	calls := counter{}
+	cached := newCache(t).Pages(time.Minute)(calls)
+
+	// a new session, like the one of csrf middleware, makes the page per
+	// visitor
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+		cached.ServeHTTP(w, r)
+	})
+
+	get(handler, "/page", "")
+	get(handler, "/page", "")
+
+	if calls["/page"] != 2 {
+		t.Errorf("handler called %d times, want 2", calls["/page"])
+	}
+}
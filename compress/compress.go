@@ -0,0 +1,313 @@
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/logging"
+	"github.com/pkg/errors"
+)
+
+const DefaultMinSize = 1024
+
+// skippedTypes are content types that are already compressed.
+var skippedTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-brotli",
+	"application/x-bzip2",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/pdf",
+	"application/octet-stream",
+}
+
+// compressibleImages are image types that are text.
+var compressibleImages = []string{
+	"image/svg+xml",
+	"image/x-icon",
+	"image/bmp",
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+type Compress struct {
+	// MinSize is the smallest response that is compressed, unless the
+	// handler flushes earlier.
+	MinSize int
+
+	logging *logging.Logging
+
+	gzipPool   sync.Pool
+	brotliPool sync.Pool
+}
+
+// New creates compression middleware, level is the gzip compression level.
+func New(level int) *Compress {
+	c := &Compress{
+		MinSize: DefaultMinSize,
+	}
+
+	c.gzipPool.New = func() interface{} {
+		w, err := gzip.NewWriterLevel(nil, level)
+		if err != nil {
+			w = gzip.NewWriter(nil)
+		}
+		return w
+	}
+	c.brotliPool.New = func() interface{} {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}
+
+	return c
+}
+
+func (c *Compress) Dependencies() []string {
+	return []string{"Logging"}
+}
+
+func (c *Compress) Configure(app *gongo.App) error {
+	return app.Lookup("Logging", &c.logging)
+}
+
+func (c *Compress) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := Negotiate(r.Header.Get("Accept-Encoding"), "br", "gzip")
+		if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: w,
+			compress:       c,
+			encoding:       encoding,
+		}
+
+		// not deferred, so responses of panicking handlers are not
+		// written and the error page can still be rendered
+		next.ServeHTTP(cw, r)
+
+		if err := cw.Close(); err != nil {
+			c.logging.WithRequest(r).WithError(err).Debug("could not finish compressed response")
+		}
+	})
+}
+
+// Negotiate returns the encoding from offers preferred by Accept-Encoding
+// header, earlier offers win ties. Empty string means identity.
+func Negotiate(acceptEncoding string, offers ...string) string {
+	accepted := make(map[string]float64)
+	for _, a := range gongo.ParseAccept(acceptEncoding) {
+		accepted[a.Value] = a.Q
+	}
+
+	best := ""
+	bestQ := 0.0
+	for _, offer := range offers {
+		// explicitly listed encodings override the wildcard
+		q, ok := accepted[offer]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best = offer
+			bestQ = q
+		}
+	}
+
+	return best
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+
+	compress *Compress
+	encoding string
+
+	status  int
+	buf     []byte
+	decided bool
+	encoder encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+	// informational responses are sent immediately
+	if status >= 100 && status < 200 {
+		cw.ResponseWriter.WriteHeader(status)
+		cw.status = 0
+	}
+}
+
+func (cw *compressWriter) Write(data []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	if !cw.decided {
+		cw.buf = append(cw.buf, data...)
+		if len(cw.buf) < cw.compress.MinSize {
+			return len(data), nil
+		}
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Write(data)
+	}
+	return cw.ResponseWriter.Write(data)
+}
+
+// Flush starts compression even if the response is smaller than MinSize,
+// because the handler is streaming.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			return
+		}
+		if err := cw.start(true); err != nil {
+			return
+		}
+	}
+	if cw.encoder != nil {
+		if err := cw.encoder.Flush(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	cw.decided = true
+	return hijacker.Hijack()
+}
+
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 {
+			return nil
+		}
+		if err := cw.start(false); err != nil {
+			return err
+		}
+	}
+
+	if cw.encoder == nil {
+		return nil
+	}
+
+	err := cw.encoder.Close()
+	cw.release()
+	return err
+}
+
+// start writes header and buffered data, compressed if the response is
+// eligible.
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+
+	header := cw.Header()
+	if compress && cw.compressible() {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", cw.encoding)
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		if cw.encoding == "br" {
+			cw.encoder = cw.compress.brotliPool.Get().(*brotli.Writer)
+		} else {
+			cw.encoder = cw.compress.gzipPool.Get().(*gzip.Writer)
+		}
+		cw.encoder.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (cw *compressWriter) compressible() bool {
+	header := cw.Header()
+
+	if cw.status < 200 || cw.status == http.StatusNoContent || cw.status == http.StatusNotModified || cw.status == http.StatusPartialContent {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(cw.buf)
+		header.Set("Content-Type", contentType)
+	}
+	contentType = strings.ToLower(contentType)
+
+	for _, t := range compressibleImages {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	for _, t := range skippedTypes {
+		if strings.HasPrefix(contentType, t) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (cw *compressWriter) release() {
+	switch encoder := cw.encoder.(type) {
+	case *gzip.Writer:
+		encoder.Reset(nil)
+		cw.compress.gzipPool.Put(encoder)
+	case *brotli.Writer:
+		encoder.Reset(nil)
+		cw.compress.brotliPool.Put(encoder)
+	}
+	cw.encoder = nil
+}
@@ -0,0 +1,113 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/matematik7/gongo/logging"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, br", "br"},
+		{"GZIP, deflate", "gzip"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"*", "br"},
+		{"*, br;q=0", "gzip"},
+		{"gzip;q=0, br;q=0", ""},
+	}
+	for _, test := range tests {
+		if got := Negotiate(test.acceptEncoding, "br", "gzip"); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.acceptEncoding, got, test.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	c := New(gzip.DefaultCompression)
+	c.MinSize = 10
+	c.logging = logging.New(false)
+
+	large := strings.Repeat("compress me ", 10)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		header         map[string]string
+		body           string
+		flush          bool
+		encoding       string
+		etag           string
+	}{
+		{name: "small", acceptEncoding: "gzip", body: "small", encoding: ""},
+		{name: "small flushed", acceptEncoding: "gzip", body: "small", flush: true, encoding: "gzip"},
+		{name: "gzip", acceptEncoding: "gzip", body: large, encoding: "gzip"},
+		{name: "brotli", acceptEncoding: "gzip, br", body: large, encoding: "br"},
+		{name: "not accepted", acceptEncoding: "", body: large, encoding: ""},
+		{name: "skipped type", acceptEncoding: "gzip", header: map[string]string{"Content-Type": "image/png"}, body: large, encoding: ""},
+		{name: "text image", acceptEncoding: "gzip", header: map[string]string{"Content-Type": "image/svg+xml"}, body: large, encoding: "gzip"},
+		{name: "weak etag", acceptEncoding: "gzip", header: map[string]string{"ETag": `"abc"`}, body: large, encoding: "gzip", etag: `W/"abc"`},
+		{name: "weak etag kept", acceptEncoding: "gzip", header: map[string]string{"ETag": `W/"abc"`}, body: large, encoding: "gzip", etag: `W/"abc"`},
+		{name: "uncompressed etag", acceptEncoding: "", header: map[string]string{"ETag": `"abc"`}, body: large, encoding: "", etag: `"abc"`},
+		{name: "already encoded", acceptEncoding: "gzip", header: map[string]string{"Content-Encoding": "custom"}, body: large, encoding: "custom"},
+	}
+	for _, test := range tests {
+		handler := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range test.header {
+				w.Header().Set(name, value)
+			}
+			w.Write([]byte(test.body))
+			if test.flush {
+				w.(http.Flusher).Flush()
+			}
+		}))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if encoding := w.Header().Get("Content-Encoding"); encoding != test.encoding {
+			t.Errorf("%s: got Content-Encoding %q, want %q", test.name, encoding, test.encoding)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("%s: got Vary %q", test.name, vary)
+		}
+		if etag := w.Header().Get("ETag"); etag != test.etag {
+			t.Errorf("%s: got ETag %q, want %q", test.name, etag, test.etag)
+		}
+
+		var body io.Reader = w.Body
+		switch test.encoding {
+		case "gzip":
+			gr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			body = gr
+		case "br":
+			body = brotli.NewReader(w.Body)
+		}
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if string(data) != test.body {
+			t.Errorf("%s: got body %q", test.name, data)
+		}
+	}
+}
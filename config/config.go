@@ -0,0 +1,331 @@
+package config
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/matematik7/gongo"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Configurable is implemented by components that read their settings from
+// Config. ConfigStruct must return a pointer to a struct (or a map of
+// structs) that is filled before the component is configured, so
+// components should also depend on Config.
+//
+// Struct fields support `default:"value"` for defaults, govalidator
+// `valid:"..."` tags for validation and `secret:"true"` to redact the
+// value in Dump.
+type Configurable interface {
+	ConfigKey() string
+	ConfigStruct() interface{}
+}
+
+const redacted = "******"
+
+type Config struct {
+	file      string
+	envPrefix string
+
+	viper    *viper.Viper
+	sections map[string]interface{}
+	keys     []string
+}
+
+// New creates config that reads file (if not empty) and environment
+// variables named envPrefix_SECTION_FIELD.
+func New(file, envPrefix string) *Config {
+	return &Config{
+		file:      file,
+		envPrefix: envPrefix,
+		viper:     viper.New(),
+		sections:  make(map[string]interface{}),
+	}
+}
+
+func (c *Config) Configure(app *gongo.App) error {
+	if c.file != "" {
+		c.viper.SetConfigFile(c.file)
+		if err := c.viper.ReadInConfig(); err != nil {
+			return errors.Wrapf(err, "could not read config file %s", c.file)
+		}
+	}
+
+	c.viper.SetEnvPrefix(c.envPrefix)
+	c.viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	c.viper.AutomaticEnv()
+
+	var errs gongo.MultiError
+	for _, name := range app.Names() {
+		if configurable, ok := app.Get(name).(Configurable); ok {
+			if err := c.Load(configurable.ConfigKey(), configurable.ConfigStruct()); err != nil {
+				errs = append(errs, errors.Wrapf(err, "invalid config for %s", name))
+			}
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// Viper returns underlying viper instance for settings that do not fit
+// into a config struct.
+func (c *Config) Viper() *viper.Viper {
+	return c.viper
+}
+
+// Load fills target with settings under key, applies defaults and
+// validates it. All invalid fields are reported in one error.
+func (c *Config) Load(key string, target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return errors.Errorf("config target for %s must be a non-nil pointer, got %T", key, target)
+	}
+
+	keys := c.prepare(key, value.Elem().Type())
+
+	// UnmarshalKey does not merge defaults and environment variables of
+	// nested keys, so the section is taken from all settings instead.
+	// AllSettings loses environment variables of keys nested in a map of
+	// the config file, so bound keys are set again.
+	settings := c.viper.AllSettings()
+	for _, k := range keys {
+		if c.viper.IsSet(k) {
+			setKey(settings, k, c.viper.Get(k))
+		}
+	}
+
+	var section interface{} = settings
+	for _, part := range strings.Split(strings.ToLower(key), ".") {
+		if m, ok := section.(map[string]interface{}); ok {
+			section = m[part]
+		} else {
+			section = nil
+		}
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           target,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not create decoder")
+	}
+	if err := decoder.Decode(section); err != nil {
+		return errors.Wrapf(err, "could not decode %s", key)
+	}
+
+	if _, ok := c.sections[key]; !ok {
+		c.keys = append(c.keys, key)
+	}
+	c.sections[key] = target
+
+	return validate(key, value.Elem()).ErrorOrNil()
+}
+
+// prepare binds environment variables and sets defaults for all fields of
+// t, because viper does not see them when unmarshaling otherwise. It
+// returns the bound keys.
+func (c *Config) prepare(key string, t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var keys []string
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			fieldKey := key + "." + fieldName(field)
+
+			if def, ok := field.Tag.Lookup("default"); ok {
+				c.viper.SetDefault(fieldKey, def)
+			}
+			c.viper.BindEnv(fieldKey)
+			keys = append(keys, fieldKey)
+
+			keys = append(keys, c.prepare(fieldKey, field.Type)...)
+		}
+	case reflect.Map:
+		// map keys are not known in advance, find them in environment
+		elem := t.Elem()
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return keys
+		}
+
+		prefix := c.envName(key) + "_"
+		for _, env := range os.Environ() {
+			name := strings.SplitN(env, "=", 2)[0]
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+
+			for i := 0; i < elem.NumField(); i++ {
+				field := elem.Field(i)
+				suffix := "_" + strings.ToUpper(fieldName(field))
+				if field.PkgPath != "" || !strings.HasSuffix(name, suffix) {
+					continue
+				}
+
+				mapKey := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix))
+				if mapKey != "" {
+					fieldKey := key + "." + mapKey + "." + fieldName(field)
+					c.viper.BindEnv(fieldKey, name)
+					keys = append(keys, fieldKey)
+				}
+			}
+		}
+	}
+
+	return keys
+}
+
+// setKey sets value of dotted key in nested settings maps.
+func setKey(settings map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := settings[part].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			settings[part] = next
+		}
+		settings = next
+	}
+	settings[parts[len(parts)-1]] = value
+}
+
+func (c *Config) envName(key string) string {
+	name := strings.ToUpper(strings.Replace(key, ".", "_", -1))
+	if c.envPrefix != "" {
+		name = strings.ToUpper(c.envPrefix) + "_" + name
+	}
+	return name
+}
+
+func validate(key string, value reflect.Value) gongo.MultiError {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	var errs gongo.MultiError
+
+	switch value.Kind() {
+	case reflect.Struct:
+		errs = append(errs, checkRequired(key, value)...)
+
+		if _, err := govalidator.ValidateStruct(value.Interface()); err != nil {
+			if validationErrs, ok := err.(govalidator.Errors); ok {
+				for _, validationErr := range validationErrs.Errors() {
+					errs = append(errs, errors.Errorf("%s.%s", key, validationErr))
+				}
+			} else {
+				errs = append(errs, errors.Wrap(err, key))
+			}
+		}
+	case reflect.Map:
+		for _, mapKey := range value.MapKeys() {
+			errs = append(errs, validate(key+"."+mapKey.String(), value.MapIndex(mapKey))...)
+		}
+	}
+
+	return errs
+}
+
+// checkRequired reports empty required fields of value and its nested
+// structs, because govalidator only checks required for nil pointers, maps
+// and slices.
+func checkRequired(key string, value reflect.Value) gongo.MultiError {
+	var errs gongo.MultiError
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		fieldKey := key + "." + fieldName(field)
+
+		if gongo.IsRequired(field) && value.Field(i).IsZero() {
+			errs = append(errs, errors.Errorf("%s is required", fieldKey))
+		} else if value.Field(i).Kind() == reflect.Struct {
+			errs = append(errs, checkRequired(fieldKey, value.Field(i))...)
+		}
+	}
+	return errs
+}
+
+// Dump writes effective configuration of all loaded sections as JSON, with
+// secret fields redacted.
+func (c *Config) Dump(w io.Writer) error {
+	effective := make(map[string]interface{}, len(c.keys))
+	for _, key := range c.keys {
+		effective[key] = redact(reflect.ValueOf(c.sections[key]))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(effective); err != nil {
+		return errors.Wrap(err, "could not encode config")
+	}
+
+	return nil
+}
+
+func redact(value reflect.Value) interface{} {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		result := make(map[string]interface{}, value.NumField())
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if field.Tag.Get("secret") == "true" {
+				if !value.Field(i).IsZero() {
+					result[fieldName(field)] = redacted
+				}
+				continue
+			}
+			result[fieldName(field)] = redact(value.Field(i))
+		}
+		return result
+	case reflect.Map:
+		result := make(map[string]interface{}, value.Len())
+		for _, mapKey := range value.MapKeys() {
+			result[mapKey.String()] = redact(value.MapIndex(mapKey))
+		}
+		return result
+	}
+
+	return value.Interface()
+}
+
+func fieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]; name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matematik7/gongo"
+)
+
+type serverConfig struct {
+	Addr    string        `default:":8080"`
+	Timeout time.Duration `default:"30s"`
+	Hosts   []string
+	DB      struct {
+		URL      string `valid:"required"`
+		Password string `secret:"true"`
+	}
+}
+
+type providerConfig struct {
+	Key    string `valid:"required"`
+	Secret string `secret:"true"`
+}
+
+func setenv(t *testing.T, env map[string]string) {
+	for name, value := range env {
+		os.Setenv(name, value)
+	}
+	t.Cleanup(func() {
+		for name := range env {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+func newConfig(t *testing.T, file string) *Config {
+	c := New(file, "test")
+	if err := c.Configure(gongo.New()); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestLoadDefaultsAndEnv(t *testing.T) {
+	setenv(t, map[string]string{
+		"TEST_SERVER_TIMEOUT":     "1m",
+		"TEST_SERVER_HOSTS":       "a.com,b.com",
+		"TEST_SERVER_DB_URL":      "postgres://localhost/test",
+		"TEST_SERVER_DB_PASSWORD": "hunter2",
+	})
+
+	c := newConfig(t, "")
+	var config serverConfig
+	if err := c.Load("server", &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Addr != ":8080" {
+		t.Errorf("Addr: got %q", config.Addr)
+	}
+	if config.Timeout != time.Minute {
+		t.Errorf("Timeout: got %s", config.Timeout)
+	}
+	if len(config.Hosts) != 2 || config.Hosts[0] != "a.com" || config.Hosts[1] != "b.com" {
+		t.Errorf("Hosts: got %v", config.Hosts)
+	}
+	if config.DB.URL != "postgres://localhost/test" || config.DB.Password != "hunter2" {
+		t.Errorf("DB: got %+v", config.DB)
+	}
+
+	var dump bytes.Buffer
+	if err := c.Dump(&dump); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(dump.String(), "hunter2") || !strings.Contains(dump.String(), redacted) {
+		t.Errorf("secret is not redacted:\n%s", dump.String())
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.json")
+	data := `{"server": {"addr": ":9000", "db": {"url": "sqlite://file"}}}`
+	if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// environment overrides the file
+	setenv(t, map[string]string{"TEST_SERVER_DB_URL": "sqlite://env"})
+
+	var config serverConfig
+	if err := newConfig(t, file).Load("server", &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Addr != ":9000" || config.Timeout != 30*time.Second || config.DB.URL != "sqlite://env" {
+		t.Errorf("got %+v", config)
+	}
+}
+
+func TestLoadMapFromEnv(t *testing.T) {
+	setenv(t, map[string]string{
+		"TEST_GOTH_GITHUB_KEY":    "github-key",
+		"TEST_GOTH_GITHUB_SECRET": "github-secret",
+		"TEST_GOTH_GITLAB_KEY":    "gitlab-key",
+	})
+
+	config := map[string]providerConfig{}
+	if err := newConfig(t, "").Load("goth", &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(config) != 2 || config["github"].Key != "github-key" || config["github"].Secret != "github-secret" || config["gitlab"].Key != "gitlab-key" {
+		t.Errorf("got %+v", config)
+	}
+}
+
+func TestLoadRequired(t *testing.T) {
+	var config serverConfig
+	err := newConfig(t, "").Load("server", &config)
+	if err == nil || !strings.Contains(err.Error(), "server.db.url is required") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package csrf
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/flosch/pongo2"
+	"github.com/gorilla/sessions"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+)
+
+type key int
+
+const secretKey key = iota
+
+const (
+	FieldName  = "csrf_token"
+	HeaderName = "X-CSRF-Token"
+
+	sessionName  = "csrf"
+	secretLength = 32
+)
+
+var safeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// Exempter is implemented by components with routes that can not send a
+// token, such as endpoints receiving reports or callbacks from other sites.
+type Exempter interface {
+	CSRFExempt() []string
+}
+
+// SameOriginer is implemented by components with forms rendered by third
+// party code, which can not include a token. Their prefixes are allowed
+// without a token for same origin requests, see AllowSameOrigin.
+type SameOriginer interface {
+	CSRFSameOrigin() []string
+}
+
+type CSRF struct {
+	store  sessions.Store
+	render *render.Render
+
+	exempt     []string
+	sameOrigin []string
+}
+
+func New() *CSRF {
+	return &CSRF{}
+}
+
+func (c *CSRF) Dependencies() []string {
+	return []string{"Store", "Render"}
+}
+
+func (c *CSRF) Configure(app *gongo.App) error {
+	if err := app.Lookup("Store", &c.store); err != nil {
+		return err
+	}
+	if err := app.Lookup("Render", &c.render); err != nil {
+		return err
+	}
+
+	for _, name := range app.Names() {
+		if exempter, ok := app.Get(name).(Exempter); ok {
+			c.Exempt(exempter.CSRFExempt()...)
+		}
+		if sameOriginer, ok := app.Get(name).(SameOriginer); ok {
+			c.AllowSameOrigin(sameOriginer.CSRFSameOrigin()...)
+		}
+	}
+
+	c.render.AddContextFunc(func(r *http.Request, ctx render.Context) {
+		token := Token(r)
+		ctx["csrf_token"] = token
+		ctx["csrf_field"] = pongo2.AsSafeValue(fmt.Sprintf(
+			`<input type="hidden" name="%s" value="%s">`, FieldName, html.EscapeString(token),
+		))
+	})
+
+	return nil
+}
+
+// Exempt disables verification for requests with path starting with any
+// of prefixes, such as endpoints that receive callbacks from other sites.
+func (c *CSRF) Exempt(prefixes ...string) {
+	c.exempt = append(c.exempt, prefixes...)
+}
+
+// AllowSameOrigin accepts requests with path starting with any of prefixes
+// without a token, when their Origin (or Referer) header matches the
+// host. Use it for forms rendered by third party code, such as qor admin.
+func (c *CSRF) AllowSameOrigin(prefixes ...string) {
+	c.sameOrigin = append(c.sameOrigin, prefixes...)
+}
+
+func (c *CSRF) isExempt(r *http.Request) bool {
+	if hasPrefix(r.URL.Path, c.exempt) {
+		return true
+	}
+
+	if hasPrefix(r.URL.Path, c.sameOrigin) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			origin = r.Header.Get("Referer")
+		}
+		if u, err := url.Parse(origin); err == nil && u.Host != "" && u.Host == r.Host {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if gongo.HasPathPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware stores per-session secret in the session store and verifies
+// token of requests with unsafe methods, from form field or header.
+func (c *CSRF) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// invalid cookie, for example after secret key change, still
+		// returns a new session, which replaces it
+		session, err := c.store.Get(r, sessionName)
+		if session == nil {
+			c.render.Error(w, r, errors.Wrap(err, "could not get csrf session"))
+			return
+		}
+
+		secret, ok := session.Values["secret"].([]byte)
+		if !ok || len(secret) != secretLength {
+			secret, err = randomBytes(secretLength)
+			if err != nil {
+				c.render.Error(w, r, errors.Wrap(err, "could not generate csrf secret"))
+				return
+			}
+
+			session.Values["secret"] = secret
+			if err := session.Save(r, w); err != nil {
+				c.render.Error(w, r, errors.Wrap(err, "could not save csrf session"))
+				return
+			}
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), secretKey, &requestSecret{secret: secret}))
+		w.Header().Add("Vary", "Cookie")
+
+		if !safeMethods[r.Method] && !c.isExempt(r) {
+			token := r.Header.Get(HeaderName)
+			if token == "" {
+				token = r.PostFormValue(FieldName)
+			}
+
+			if !verify(secret, token) {
+				c.render.Forbidden(w, r)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestSecret is the secret of the request, used remembers if a token
+// was rendered.
+type requestSecret struct {
+	secret []byte
+	used   int32
+}
+
+// Token returns a new token for the request. Tokens are masked with a
+// random pad, so they differ on every response (BREACH mitigation).
+func Token(r *http.Request) string {
+	rs, ok := r.Context().Value(secretKey).(*requestSecret)
+	if !ok {
+		return ""
+	}
+	atomic.StoreInt32(&rs.used, 1)
+
+	pad, err := randomBytes(len(rs.secret))
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(append(pad, xor(pad, rs.secret)...))
+}
+
+// CacheSkip implements cache.Skipper, pages with a token are per session.
+func (c *CSRF) CacheSkip(r *http.Request, body []byte) bool {
+	rs, ok := r.Context().Value(secretKey).(*requestSecret)
+	return ok && atomic.LoadInt32(&rs.used) == 1
+}
+
+func verify(secret []byte, token string) bool {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(data) != 2*len(secret) {
+		return false
+	}
+
+	pad, masked := data[:len(secret)], data[len(secret):]
+
+	return subtle.ConstantTimeCompare(xor(pad, masked), secret) == 1
+}
+
+func xor(a, b []byte) []byte {
+	result := make([]byte, len(a))
+	for i := range a {
+		result[i] = a[i] ^ b[i]
+	}
+	return result
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
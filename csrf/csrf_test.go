@@ -0,0 +1,135 @@
+package csrf
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matematik7/gongo/internal/apptest"
+)
+
+func TestVerify(t *testing.T) {
+	secret, err := randomBytes(secretLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := randomBytes(secretLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), secretKey, &requestSecret{secret: secret}))
+
+	first, second := Token(req), Token(req)
+	if first == second {
+		t.Error("tokens are not masked")
+	}
+
+	tests := []struct {
+		name   string
+		secret []byte
+		token  string
+		valid  bool
+	}{
+		{"first", secret, first, true},
+		{"second", secret, second, true},
+		{"other secret", other, first, false},
+		{"empty", secret, "", false},
+		{"truncated", secret, first[:len(first)-2], false},
+		{"invalid base64", secret, "!" + first[1:], false},
+		{"unmasked secret", secret, string(secret), false},
+	}
+	for _, test := range tests {
+		if valid := verify(test.secret, test.token); valid != test.valid {
+			t.Errorf("%s: got %v, want %v", test.name, valid, test.valid)
+		}
+	}
+}
+
+func TestTokenWithoutMiddleware(t *testing.T) {
+	if token := Token(httptest.NewRequest("GET", "/", nil)); token != "" {
+		t.Errorf("got token %q outside of middleware", token)
+	}
+}
+
+type exempter struct{}
+
+func (exempter) CSRFExempt() []string {
+	return []string{"/hooks/"}
+}
+
+func TestMiddleware(t *testing.T) {
+	c := New()
+
+	app := apptest.New(nil)
+	app.Register("CSRF", c)
+	app.Register("Hooks", exempter{})
+	if err := app.Configure(); err != nil {
+		t.Fatal(err)
+	}
+
+	var token string
+	handler := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = Token(r)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/form", nil))
+	if w.Code != http.StatusOK || token == "" {
+		t.Fatalf("GET: got status %d and token %q", w.Code, token)
+	}
+	cookies := w.Result().Cookies()
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}{
+		{"valid token", "/form", token, http.StatusOK},
+		{"missing token", "/form", "", http.StatusForbidden},
+		{"invalid token", "/form", token[1:], http.StatusForbidden},
+		{"exempt", "/hooks/github", "", http.StatusOK},
+		{"exempt prefix is a path", "/hooksmith", "", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", test.path, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		if test.token != "" {
+			req.Header.Set(HeaderName, test.token)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("%s: got status %d, want %d", test.name, w.Code, test.status)
+		}
+	}
+}
+
+func TestCacheSkip(t *testing.T) {
+	c := New()
+	app := apptest.New(nil)
+	app.Register("CSRF", c)
+	if err := app.Configure(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, useToken := range []bool{false, true} {
+		var skip bool
+		c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if useToken {
+				Token(r)
+			}
+			skip = c.CacheSkip(r, nil)
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		if skip != useToken {
+			t.Errorf("token used %v: got skip %v", useToken, skip)
+		}
+	}
+}
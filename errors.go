@@ -0,0 +1,22 @@
+package gongo
+
+import "strings"
+
+// MultiError collects errors that should be reported together.
+type MultiError []error
+
+func (me MultiError) Error() string {
+	msgs := make([]string, len(me))
+	for i, err := range me {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ErrorOrNil returns nil when no errors were collected.
+func (me MultiError) ErrorOrNil() error {
+	if len(me) == 0 {
+		return nil
+	}
+	return me
+}
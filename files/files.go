@@ -11,6 +11,7 @@ import (
 	"github.com/jinzhu/gorm"
 	"github.com/matematik7/gongo"
 	"github.com/matematik7/gongo/files/storage"
+	"github.com/matematik7/gongo/logging"
 	"github.com/matematik7/gongo/render"
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
@@ -29,14 +30,28 @@ func New(storage storage.Storage) *Files {
 	}
 }
 
-func (f *Files) Configure(app gongo.App) error {
-	f.db = app["DB"].(*gorm.DB)
+func (f *Files) Dependencies() []string {
+	return []string{"DB", "Logging", "Render"}
+}
+
+func (f *Files) Configure(app *gongo.App) error {
+	if err := app.Lookup("DB", &f.db); err != nil {
+		return err
+	}
+	var l *logging.Logging
+	if err := app.Lookup("Logging", &l); err != nil {
+		return err
+	}
+	var r *render.Render
+	if err := app.Lookup("Render", &r); err != nil {
+		return err
+	}
 
-	app["Render"].(*render.Render).AddContextFunc(func(r *http.Request, ctx render.Context) {
+	r.AddContextFunc(func(r *http.Request, ctx render.Context) {
 		ctx["file_url"] = func(file FileItf) string {
 			url, err := f.URL(file)
 			if err != nil {
-				// TODO: handle this error
+				l.WithRequest(r).WithError(err).Errorf("could not get url for file %s", file.GetID())
 			}
 			return url
 		}
@@ -143,6 +158,16 @@ func (f *Files) Delete(file FileItf) error {
 	return nil
 }
 
+// Open returns content of file, it has to be closed by the caller. The
+// storage has to implement storage.Opener.
+func (f *Files) Open(file FileItf) (io.ReadCloser, error) {
+	opener, ok := f.storage.(storage.Opener)
+	if !ok {
+		return nil, errors.Errorf("storage %T can not open files", f.storage)
+	}
+	return opener.Open(file.GetID().String())
+}
+
 func (f *Files) URL(file FileItf) (string, error) {
 	return f.storage.URL(file.GetID().String())
 }
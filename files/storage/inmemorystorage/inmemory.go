@@ -1,6 +1,7 @@
 package inmemorystorage
 
 import (
+	"bytes"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -46,6 +47,18 @@ func (ims *InMemoryStorage) Save(name string, f io.Reader) error {
 	return nil
 }
 
+func (ims *InMemoryStorage) Open(name string) (io.ReadCloser, error) {
+	ims.mutex.RLock()
+	defer ims.mutex.RUnlock()
+
+	data, ok := ims.data[name]
+	if !ok {
+		return nil, errors.Errorf("file %s not found", name)
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
 func (ims *InMemoryStorage) Delete(name string) error {
 	ims.mutex.Lock()
 	defer ims.mutex.Unlock()
@@ -55,6 +68,10 @@ func (ims *InMemoryStorage) Delete(name string) error {
 	return nil
 }
 
+func (ims *InMemoryStorage) Prefix() string {
+	return ims.urlPrefix
+}
+
 func (ims *InMemoryStorage) ServeMux() http.Handler {
 	router := chi.NewRouter()
 
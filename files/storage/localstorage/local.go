@@ -53,6 +53,17 @@ func (ls *LocalStorage) Save(name string, input io.Reader) error {
 	return nil
 }
 
+func (ls *LocalStorage) Open(name string) (io.ReadCloser, error) {
+	pathName := ls.getPathName(name)
+
+	f, err := os.Open(pathName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open file %s", pathName)
+	}
+
+	return f, nil
+}
+
 func (ls *LocalStorage) Delete(name string) error {
 	pathName := ls.getPathName(name)
 
@@ -77,6 +88,10 @@ func (ls *LocalStorage) List(prefix string) ([]string, error) {
 	return results, nil
 }
 
+func (ls *LocalStorage) Prefix() string {
+	return ls.urlPrefix
+}
+
 func (ls *LocalStorage) ServeMux() http.Handler {
 	router := chi.NewRouter()
 
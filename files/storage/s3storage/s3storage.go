@@ -65,6 +65,18 @@ func (s *S3Storage) Save(name string, input io.Reader) error {
 	return nil
 }
 
+func (s *S3Storage) Open(name string) (io.ReadCloser, error) {
+	output, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &name,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get file %s", name)
+	}
+
+	return output.Body, nil
+}
+
 func (s *S3Storage) Delete(name string) error {
 	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
 		Bucket: &s.bucket,
@@ -8,3 +8,9 @@ type Storage interface {
 	Delete(name string) error
 	List(prefix string) ([]string, error)
 }
+
+// Opener is implemented by storages that can read saved files back, for
+// example to attach them to mails.
+type Opener interface {
+	Open(name string) (io.ReadCloser, error)
+}
@@ -0,0 +1,266 @@
+package forms
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matematik7/gongo/i18n"
+	"github.com/pkg/errors"
+)
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	durationType    = reflect.TypeOf(time.Duration(0))
+	fileHeaderType  = reflect.TypeOf(&multipart.FileHeader{})
+	unmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	marshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// timeLayouts are tried in order when decoding times, they cover inputs of
+// type datetime-local and date.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// walk calls fn for every field of struct value that holds a form value.
+// Embedded structs are flattened, other nested structs are named
+// parent.field.
+func walk(value reflect.Value, pathPrefix, namePrefix string, fn func(field reflect.StructField, v reflect.Value, path, name string)) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("form") == "-" {
+			continue
+		}
+
+		v := value.Field(i)
+		if field.Type.Kind() == reflect.Struct && !isScalar(field.Type) {
+			if field.Anonymous {
+				walk(v, pathPrefix, namePrefix, fn)
+			} else {
+				walk(v, pathPrefix+field.Name+".", namePrefix+formName(field)+".", fn)
+			}
+			continue
+		}
+
+		fn(field, v, pathPrefix+field.Name, namePrefix+formName(field))
+	}
+}
+
+func isScalar(t reflect.Type) bool {
+	return t == timeType || reflect.PtrTo(t).Implements(unmarshalerType)
+}
+
+// decode sets fields of value from submitted values and files.
+func (f *Form) decode(ctx context.Context, value reflect.Value) {
+	walk(value, "", "", func(field reflect.StructField, v reflect.Value, path, name string) {
+		f.names[path] = name
+
+		switch field.Type {
+		case fileHeaderType:
+			if files := f.Files[name]; len(files) > 0 {
+				v.Set(reflect.ValueOf(files[0]))
+			}
+			return
+		case reflect.SliceOf(fileHeaderType):
+			if files, ok := f.Files[name]; ok {
+				v.Set(reflect.ValueOf(files))
+			}
+			return
+		}
+
+		values, ok := f.Values[name]
+		if !ok {
+			return
+		}
+
+		var err error
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(field.Type, len(values), len(values))
+			for i, s := range values {
+				if err = decodeValue(slice.Index(i), s); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				v.Set(slice)
+			}
+		} else if len(values) > 0 {
+			// the last value wins, so a hidden input before a checkbox
+			// can provide the unchecked value
+			err = decodeValue(v, values[len(values)-1])
+		}
+
+		if err != nil {
+			f.AddError(name, i18n.T(ctx, invalidMessage))
+		}
+	})
+}
+
+func decodeValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem())
+		if err := decodeValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if v.Type() != timeType && v.CanAddr() && v.Addr().Type().Implements(unmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	s = strings.TrimSpace(s)
+
+	switch v.Type() {
+	case timeType:
+		if s == "" {
+			v.Set(reflect.Zero(timeType))
+			return nil
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return errors.Errorf("could not parse time %s", s)
+	case durationType:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		switch strings.ToLower(s) {
+		case "", "off", "no":
+			v.SetBool(false)
+		case "on", "yes":
+			v.SetBool(true)
+		default:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return err
+			}
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			v.SetUint(0)
+			return nil
+		}
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return errors.Errorf("unsupported field type %s", v.Type())
+	}
+
+	return nil
+}
+
+// encode sets form values from fields of value.
+func (f *Form) encode(value reflect.Value) {
+	walk(value, "", "", func(field reflect.StructField, v reflect.Value, path, name string) {
+		f.names[path] = name
+
+		if field.Type == fileHeaderType || field.Type == reflect.SliceOf(fileHeaderType) {
+			return
+		}
+
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() != reflect.Uint8 {
+			values := make([]string, v.Len())
+			for i := range values {
+				values[i] = encodeValue(v.Index(i))
+			}
+			f.Values[name] = values
+			return
+		}
+
+		f.Values[name] = []string{encodeValue(v)}
+	})
+}
+
+func encodeValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		if value.Hour() == 0 && value.Minute() == 0 && value.Second() == 0 {
+			return value.Format("2006-01-02")
+		}
+		return value.Format("2006-01-02T15:04")
+	case time.Duration:
+		return value.String()
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(marshalerType) {
+		text, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	}
+
+	return fmt.Sprint(v.Interface())
+}
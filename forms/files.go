@@ -0,0 +1,50 @@
+package forms
+
+import (
+	"mime/multipart"
+	"path"
+	"strings"
+
+	"github.com/matematik7/gongo/files"
+	"github.com/pkg/errors"
+)
+
+// ErrNoFile is returned when no file was uploaded in the form field.
+var ErrNoFile = errors.New("no file uploaded")
+
+// SaveFile stores file uploaded in form field name with fs.
+func (f *Form) SaveFile(fs *files.Files, name, description string) (files.File, error) {
+	header := f.File(name)
+	if header == nil {
+		return files.File{}, ErrNoFile
+	}
+
+	input, err := header.Open()
+	if err != nil {
+		return files.File{}, errors.Wrapf(err, "could not open uploaded file %s", name)
+	}
+	defer input.Close()
+
+	return fs.NewFile(input, fileName(header), description)
+}
+
+// SaveImage stores image uploaded in form field name with fs.
+func (f *Form) SaveImage(fs *files.Files, name, description string) (files.Image, error) {
+	header := f.File(name)
+	if header == nil {
+		return files.Image{}, ErrNoFile
+	}
+
+	input, err := header.Open()
+	if err != nil {
+		return files.Image{}, errors.Wrapf(err, "could not open uploaded file %s", name)
+	}
+	defer input.Close()
+
+	return fs.NewImage(input, fileName(header), description)
+}
+
+// fileName strips directories some browsers send with the file name.
+func fileName(header *multipart.FileHeader) string {
+	return path.Base(strings.ReplaceAll(header.Filename, "\\", "/"))
+}
@@ -0,0 +1,248 @@
+package forms
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/i18n"
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxMemory is the part of multipart forms kept in memory, the rest
+// of uploaded files is stored in temporary files.
+const DefaultMaxMemory = 32 << 20
+
+const (
+	requiredMessage = "This field is required."
+	invalidMessage  = "Enter a valid value."
+)
+
+// Validator is implemented by forms with validation that can not be
+// expressed with govalidator tags. Use form.AddError to report errors.
+type Validator interface {
+	Validate(ctx context.Context, form *Form)
+}
+
+// AddValidator registers govalidator tag with custom validation function,
+// fn receives value of the field.
+func AddValidator(tag string, fn func(value interface{}) bool) {
+	govalidator.CustomTypeTagMap.Set(tag, func(i interface{}, o interface{}) bool {
+		return fn(i)
+	})
+}
+
+// Form holds submitted values and errors per field, so the page can be
+// rendered again with them.
+type Form struct {
+	Values url.Values
+	Files  map[string][]*multipart.FileHeader
+	Errors map[string]string
+
+	// names maps struct field paths to form field names
+	names map[string]string
+}
+
+// New returns form with values of target, for rendering the form before
+// it is submitted.
+func New(target interface{}) *Form {
+	f := &Form{
+		Values: url.Values{},
+		Files:  make(map[string][]*multipart.FileHeader),
+		Errors: make(map[string]string),
+		names:  make(map[string]string),
+	}
+	f.encode(reflect.Indirect(reflect.ValueOf(target)))
+	return f
+}
+
+// Bind decodes form, multipart form or json body of r into target, which
+// must be a pointer to a struct, and validates it. Fields are named by form
+// tag or the field name, fields tagged form:"-" are never bound. Only fields
+// present in the request are changed. POST, PUT and PATCH requests are bound
+// only from the body, so query parameters can not set fields the form does
+// not submit. Returned error is BadRequestError for malformed requests,
+// validation errors are stored in the form.
+func Bind(r *http.Request, target interface{}) (*Form, error) {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return nil, errors.Errorf("bind target must be a pointer to struct, got %T", target)
+	}
+
+	f := &Form{
+		Values: url.Values{},
+		Files:  make(map[string][]*multipart.FileHeader),
+		Errors: make(map[string]string),
+		names:  make(map[string]string),
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch contentType {
+	case "application/json":
+		if err := json.NewDecoder(r.Body).Decode(target); err != nil {
+			return f, render.BadRequestError{Message: i18n.T(r.Context(), "Could not decode request.")}
+		}
+		f.encode(value.Elem())
+
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(DefaultMaxMemory); err != nil {
+			return f, render.BadRequestError{Message: i18n.T(r.Context(), "Could not decode request.")}
+		}
+		f.Values = formValues(r)
+		f.Files = r.MultipartForm.File
+		f.decode(r.Context(), value.Elem())
+
+	default:
+		if err := r.ParseForm(); err != nil {
+			return f, render.BadRequestError{Message: i18n.T(r.Context(), "Could not decode request.")}
+		}
+		f.Values = formValues(r)
+		f.decode(r.Context(), value.Elem())
+	}
+
+	f.validate(r.Context(), target)
+
+	return f, nil
+}
+
+// formValues returns values of parsed r, body values for requests with a
+// body and query values otherwise.
+func formValues(r *http.Request) url.Values {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return r.PostForm
+	}
+	return r.Form
+}
+
+func (f *Form) validate(ctx context.Context, target interface{}) {
+	value := reflect.ValueOf(target).Elem()
+
+	// govalidator does not enforce required on empty strings
+	f.checkRequired(ctx, value)
+
+	if _, err := govalidator.ValidateStruct(target); err != nil {
+		var errs govalidator.Errors
+		if !errors.As(err, &errs) {
+			errs = govalidator.Errors{err}
+		}
+		for _, err := range errs.Errors() {
+			f.addValidationError(ctx, err)
+		}
+	}
+
+	if validator, ok := target.(Validator); ok {
+		validator.Validate(ctx, f)
+	}
+}
+
+func (f *Form) checkRequired(ctx context.Context, value reflect.Value) {
+	walk(value, "", "", func(field reflect.StructField, v reflect.Value, path, name string) {
+		if gongo.IsRequired(field) && v.IsZero() {
+			f.AddError(name, i18n.T(ctx, requiredMessage))
+		}
+	})
+}
+
+func (f *Form) addValidationError(ctx context.Context, err error) {
+	var errs govalidator.Errors
+	if errors.As(err, &errs) {
+		for _, err := range errs.Errors() {
+			f.addValidationError(ctx, err)
+		}
+		return
+	}
+
+	var validationErr govalidator.Error
+	if !errors.As(err, &validationErr) {
+		return
+	}
+
+	path := strings.Join(append(validationErr.Path, validationErr.Name), ".")
+	switch {
+	case validationErr.CustomErrorMessageExists:
+		f.AddError(f.name(path), i18n.T(ctx, validationErr.Err.Error()))
+	case validationErr.Validator == "required":
+		f.AddError(f.name(path), i18n.T(ctx, requiredMessage))
+	default:
+		f.AddError(f.name(path), i18n.T(ctx, invalidMessage))
+	}
+}
+
+// name returns form field name of struct field path.
+func (f *Form) name(path string) string {
+	if name, ok := f.names[path]; ok {
+		return name
+	}
+	return path
+}
+
+// AddError adds error for form field name, only the first error of a field
+// is kept.
+func (f *Form) AddError(name, msg string) {
+	if _, ok := f.Errors[name]; !ok {
+		f.Errors[name] = msg
+	}
+}
+
+func (f *Form) Valid() bool {
+	return len(f.Errors) == 0
+}
+
+// Err returns ValidationError with errors of the form, or nil if it is
+// valid.
+func (f *Form) Err() error {
+	if f.Valid() {
+		return nil
+	}
+	return render.ValidationError{Fields: f.Errors}
+}
+
+// Value returns submitted value of form field name.
+func (f *Form) Value(name string) string {
+	values := f.Values[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// File returns uploaded file of form field name or nil.
+func (f *Form) File(name string) *multipart.FileHeader {
+	if files := f.Files[name]; len(files) > 0 {
+		return files[0]
+	}
+	return nil
+}
+
+// Context exposes the form to templates as form.values.Field,
+// form.lists.Field for all values of a field, form.errors.Field and
+// form.valid.
+func (f *Form) Context() render.Context {
+	values := make(map[string]string, len(f.Values))
+	for name := range f.Values {
+		values[name] = f.Value(name)
+	}
+
+	return render.Context{
+		"values": values,
+		"lists":  map[string][]string(f.Values),
+		"errors": f.Errors,
+		"valid":  f.Valid(),
+	}
+}
+
+func formName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("form"), ",")[0]; name != "" {
+		return name
+	}
+	return field.Name
+}
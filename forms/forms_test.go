@@ -0,0 +1,158 @@
+package forms
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+)
+
+type address struct {
+	City string `form:"city" valid:"required"`
+}
+
+type profile struct {
+	Name    string `form:"name" valid:"required"`
+	Email   string `form:"email" valid:"email"`
+	Age     int    `form:"age"`
+	Admin   bool   `form:"-"`
+	Tags    []string
+	Active  bool `form:"active"`
+	Address address
+}
+
+func newRequest(method, target, body string) *http.Request {
+	var r *http.Request
+	if body == "" {
+		r = httptest.NewRequest(method, target, nil)
+	} else {
+		r = httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return r
+}
+
+func TestBindPrecedence(t *testing.T) {
+	tests := []struct {
+		method string
+		target string
+		body   string
+		name   string
+		age    int
+	}{
+		{method: "GET", target: "/?name=query&age=1", name: "query", age: 1},
+		{method: "POST", target: "/?name=query&age=1", body: "name=body", name: "body"},
+		{method: "PUT", target: "/?age=1", body: "name=body", name: "body"},
+		{method: "PATCH", target: "/?age=1", body: "name=body", name: "body"},
+	}
+	for _, test := range tests {
+		p := profile{}
+		if _, err := Bind(newRequest(test.method, test.target, test.body), &p); err != nil {
+			t.Fatalf("%s %s: %v", test.method, test.target, err)
+		}
+		if p.Name != test.name || p.Age != test.age {
+			t.Errorf("%s %s: got name %q and age %d, want %q and %d", test.method, test.target, p.Name, p.Age, test.name, test.age)
+		}
+	}
+}
+
+func TestBindIgnored(t *testing.T) {
+	p := profile{}
+	if _, err := Bind(newRequest("POST", "/", "name=a&Admin=true"), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Admin {
+		t.Error("field tagged form:\"-\" was bound")
+	}
+}
+
+func TestBindTypes(t *testing.T) {
+	p := profile{Active: true}
+	form, err := Bind(newRequest("POST", "/", "name=a&email=a@example.com&age=12&Tags=x&Tags=y&active=off&Address.city=Ljubljana"), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !form.Valid() {
+		t.Fatalf("got errors %v", form.Errors)
+	}
+
+	want := profile{Name: "a", Email: "a@example.com", Age: 12, Tags: []string{"x", "y"}, Address: address{City: "Ljubljana"}}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+}
+
+func TestBindConversionError(t *testing.T) {
+	p := profile{}
+	form, err := Bind(newRequest("POST", "/", "name=a&email=a@example.com&age=old&Address.city=x"), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := form.Errors["age"]; msg != invalidMessage {
+		t.Errorf("got age error %q", msg)
+	}
+	if len(form.Errors) != 1 {
+		t.Errorf("got errors %v", form.Errors)
+	}
+	if form.Value("age") != "old" {
+		t.Errorf("submitted value was not kept, got %q", form.Value("age"))
+	}
+}
+
+func TestBindValidation(t *testing.T) {
+	p := profile{}
+	form, err := Bind(newRequest("POST", "/", "name=&email=invalid"), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"name":         requiredMessage,
+		"email":        invalidMessage,
+		"Address.city": requiredMessage,
+	}
+	if !reflect.DeepEqual(form.Errors, want) {
+		t.Errorf("got errors %v, want %v", form.Errors, want)
+	}
+
+	validationErr, ok := form.Err().(render.ValidationError)
+	if !ok {
+		t.Fatalf("got %T, want render.ValidationError", form.Err())
+	}
+	if !reflect.DeepEqual(validationErr.Fields, want) {
+		t.Errorf("got fields %v", validationErr.Fields)
+	}
+}
+
+func TestBindJSON(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"Name": "a", "Email": "a@example.com", "Age": 3, "Address": {"City": "x"}}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	p := profile{}
+	form, err := Bind(r, &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !form.Valid() || p.Email != "a@example.com" || p.Age != 3 {
+		t.Errorf("got %+v with errors %v", p, form.Errors)
+	}
+	if form.Value("age") != "3" {
+		t.Errorf("got age value %q", form.Value("age"))
+	}
+
+	r = httptest.NewRequest("POST", "/", strings.NewReader(`{`))
+	r.Header.Set("Content-Type", "application/json")
+	if _, err := Bind(r, &p); !errors.As(err, &render.BadRequestError{}) {
+		t.Errorf("got %v, want bad request", err)
+	}
+}
+
+func TestBindTarget(t *testing.T) {
+	if _, err := Bind(newRequest("GET", "/", ""), profile{}); err == nil {
+		t.Error("non pointer target was accepted")
+	}
+}
@@ -1,9 +1,10 @@
 module github.com/matematik7/gongo
 
-go 1.13
+go 1.16
 
 require (
-	github.com/asaskevich/govalidator v0.0.0-20200108200545-475eaeb16496 // indirect
+	github.com/andybalholm/brotli v1.0.4
+	github.com/asaskevich/govalidator v0.0.0-20200108200545-475eaeb16496
 	github.com/aws/aws-sdk-go v1.28.9
 	github.com/flosch/pongo2 v0.0.0-20190707114632-bbf5a6c351f4
 	github.com/go-chi/chi v4.0.3+incompatible
@@ -12,6 +13,7 @@ require (
 	github.com/jinzhu/gorm v1.9.12
 	github.com/markbates/goth v1.61.1
 	github.com/microcosm-cc/bluemonday v1.0.2 // indirect
+	github.com/mitchellh/mapstructure v1.1.2
 	github.com/pkg/errors v0.9.1
 	github.com/qor/admin v0.0.0-20191226032843-24c19e4f3c63
 	github.com/qor/assetfs v0.0.0-20170713023933-ff57fdc13a14 // indirect
@@ -26,4 +28,5 @@ require (
 	github.com/spf13/viper v1.6.2
 	github.com/theplant/cldr v0.0.0-20190423050709-9f76f7ce4ee8 // indirect
 	github.com/xor-gate/goexif2 v1.1.0
+	golang.org/x/net v0.0.0-20190522155817-f3200d17e092
 )
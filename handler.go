@@ -0,0 +1,7 @@
+package gongo
+
+import "net/http"
+
+// HandlerFunc is a http handler that returns errors instead of rendering
+// them, see render.Render.Handler.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
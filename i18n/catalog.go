@@ -0,0 +1,211 @@
+package i18n
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// pluralFunc returns index of the plural form for n, as in gettext
+// Plural-Forms header.
+type pluralFunc func(n int) int
+
+var pluralRules = map[string]pluralFunc{
+	"ja": func(n int) int { return 0 },
+	"ko": func(n int) int { return 0 },
+	"zh": func(n int) int { return 0 },
+	"fr": func(n int) int {
+		if n > 1 {
+			return 1
+		}
+		return 0
+	},
+	"cs": czechPlural,
+	"sk": czechPlural,
+	"sl": func(n int) int {
+		switch n % 100 {
+		case 1:
+			return 0
+		case 2:
+			return 1
+		case 3, 4:
+			return 2
+		}
+		return 3
+	},
+	"pl": func(n int) int {
+		if n == 1 {
+			return 0
+		}
+		if n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20) {
+			return 1
+		}
+		return 2
+	},
+	"ru": slavicPlural,
+	"uk": slavicPlural,
+	"hr": slavicPlural,
+	"sr": slavicPlural,
+	"bs": slavicPlural,
+}
+
+func czechPlural(n int) int {
+	if n == 1 {
+		return 0
+	}
+	if n >= 2 && n <= 4 {
+		return 1
+	}
+	return 2
+}
+
+func slavicPlural(n int) int {
+	if n%10 == 1 && n%100 != 11 {
+		return 0
+	}
+	if n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20) {
+		return 1
+	}
+	return 2
+}
+
+func germanicPlural(n int) int {
+	if n == 1 {
+		return 0
+	}
+	return 1
+}
+
+func pluralRule(locale string) pluralFunc {
+	if rule, ok := pluralRules[language(locale)]; ok {
+		return rule
+	}
+	return germanicPlural
+}
+
+// catalog holds translations of one locale. Messages with plural forms
+// have one translation per form.
+type catalog struct {
+	locale   string
+	plural   pluralFunc
+	messages map[string][]string
+}
+
+func newCatalog(locale string) *catalog {
+	return &catalog{
+		locale:   locale,
+		plural:   pluralRule(locale),
+		messages: make(map[string][]string),
+	}
+}
+
+func (c *catalog) translate(msg string) string {
+	if c != nil {
+		if forms, ok := c.messages[msg]; ok && len(forms) > 0 && forms[0] != "" {
+			return forms[0]
+		}
+	}
+	return msg
+}
+
+func (c *catalog) translatePlural(singular, plural string, n int) string {
+	if c != nil {
+		if forms, ok := c.messages[singular]; ok {
+			if i := c.plural(n); i < len(forms) && forms[i] != "" {
+				return forms[i]
+			}
+		}
+	}
+
+	if germanicPlural(n) == 0 {
+		return singular
+	}
+	return plural
+}
+
+// loadCatalogs reads <locale>.json files from root of source. Every file is
+// an object mapping messages to a translation or to a list of plural forms.
+func loadCatalogs(source http.FileSystem) (map[string]*catalog, error) {
+	catalogs := make(map[string]*catalog)
+	if source == nil {
+		return catalogs, nil
+	}
+
+	dir, err := source.Open("/")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open catalogs")
+	}
+	infos, err := dir.Readdir(-1)
+	dir.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list catalogs")
+	}
+
+	for _, info := range infos {
+		if info.IsDir() || path.Ext(info.Name()) != ".json" {
+			continue
+		}
+
+		locale := normalize(strings.TrimSuffix(info.Name(), ".json"))
+		c, err := loadCatalog(source, info.Name(), locale)
+		if err != nil {
+			return nil, err
+		}
+		catalogs[locale] = c
+	}
+
+	return catalogs, nil
+}
+
+func loadCatalog(source http.FileSystem, name, locale string) (*catalog, error) {
+	f, err := source.Open("/" + name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open catalog %s", name)
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read catalog %s", name)
+	}
+
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrapf(err, "could not parse catalog %s", name)
+	}
+
+	c := newCatalog(locale)
+	for msg, value := range raw {
+		var translation string
+		if err := json.Unmarshal(value, &translation); err == nil {
+			c.messages[msg] = []string{translation}
+			continue
+		}
+
+		var forms []string
+		if err := json.Unmarshal(value, &forms); err != nil {
+			return nil, errors.Errorf("catalog %s: translation of %q must be a string or a list of plural forms", name, msg)
+		}
+		c.messages[msg] = forms
+	}
+
+	return c, nil
+}
+
+// normalize converts locale to lower case language with upper case region,
+// such as en-US.
+func normalize(locale string) string {
+	parts := strings.SplitN(strings.Replace(strings.TrimSpace(locale), "_", "-", -1), "-", 2)
+	if len(parts) == 2 {
+		return strings.ToLower(parts[0]) + "-" + strings.ToUpper(parts[1])
+	}
+	return strings.ToLower(parts[0])
+}
+
+func language(locale string) string {
+	return strings.SplitN(normalize(locale), "-", 2)[0]
+}
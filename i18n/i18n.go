@@ -0,0 +1,297 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+)
+
+type key int
+
+const (
+	requestLocaleKey key = iota
+	localeKey
+)
+
+const (
+	CookieName = "locale"
+
+	// contextKey holds localizer in template context, used by trans tags
+	contextKey = "i18n"
+)
+
+// Localer is implemented by users that have a preferred locale.
+type Localer interface {
+	GetLocale() string
+}
+
+type I18n struct {
+	defaultLocale string
+	source        http.FileSystem
+
+	catalogs map[string]*catalog
+}
+
+// New creates i18n that loads catalogs from <locale>.json files in source.
+// Source can be nil when the app is not translated yet.
+func New(defaultLocale string, source http.FileSystem) *I18n {
+	return &I18n{
+		defaultLocale: normalize(defaultLocale),
+		source:        source,
+	}
+}
+
+func (i *I18n) Dependencies() []string {
+	return []string{"Render"}
+}
+
+func (i *I18n) Configure(app *gongo.App) error {
+	catalogs, err := loadCatalogs(i.source)
+	if err != nil {
+		return errors.Wrap(err, "could not load catalogs")
+	}
+	i.catalogs = catalogs
+
+	var r *render.Render
+	if err := app.Lookup("Render", &r); err != nil {
+		return err
+	}
+
+	r.AddContextFunc(func(req *http.Request, ctx render.Context) {
+		i.addContext(req.Context(), ctx)
+	})
+	r.AddOutputContextFunc(i.addContext)
+	r.AddPreprocessor(bindTransFilter)
+
+	return nil
+}
+
+func (i *I18n) addContext(c context.Context, ctx render.Context) {
+	l := i.localizer(i.Locale(c))
+
+	ctx[contextKey] = l
+	ctx["locale"] = l.locale
+	ctx["trans"] = l.T
+	ctx["ntrans"] = l.N
+}
+
+// Locales returns all locales with a catalog and the default locale.
+func (i *I18n) Locales() []string {
+	locales := []string{i.defaultLocale}
+	for locale := range i.catalogs {
+		if locale != i.defaultLocale {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales[1:])
+	return locales
+}
+
+// Middleware stores locale preferences of the request in its context.
+func (i *I18n) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rl := requestLocale{
+			i18n:   i,
+			accept: r.Header.Get("Accept-Language"),
+		}
+		if cookie, err := r.Cookie(CookieName); err == nil {
+			rl.cookie = cookie.Value
+		}
+
+		ctx := context.WithValue(r.Context(), requestLocaleKey, rl)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type requestLocale struct {
+	i18n   *I18n
+	cookie string
+	accept string
+}
+
+// contextLocale is locale set with WithLocale and i18n that translates it.
+type contextLocale struct {
+	i18n   *I18n
+	locale string
+}
+
+// WithLocale returns ctx with locale, used instead of user and request
+// preferences. T and N translate ctx with i18n of the request ctx belongs
+// to, use (*I18n).WithLocale outside of requests.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, contextLocale{
+		i18n:   fromI18n(ctx),
+		locale: locale,
+	})
+}
+
+// WithLocale returns ctx with locale translated by i, for example when
+// rendering emails outside of requests.
+func (i *I18n) WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, contextLocale{
+		i18n:   i,
+		locale: locale,
+	})
+}
+
+// Locale returns locale for ctx, preferring locale set with WithLocale,
+// then locale of the logged in user, then the locale cookie, then
+// Accept-Language header.
+func (i *I18n) Locale(ctx context.Context) string {
+	if cl, ok := ctx.Value(localeKey).(contextLocale); ok {
+		if locale, ok := i.supported(cl.locale); ok {
+			return locale
+		}
+	}
+
+	if localer, ok := ctx.Value("user").(Localer); ok {
+		if locale, ok := i.supported(localer.GetLocale()); ok {
+			return locale
+		}
+	}
+
+	if rl, ok := ctx.Value(requestLocaleKey).(requestLocale); ok {
+		if locale, ok := i.supported(rl.cookie); ok {
+			return locale
+		}
+		for _, accepted := range parseAcceptLanguage(rl.accept) {
+			if locale, ok := i.supported(accepted); ok {
+				return locale
+			}
+		}
+	}
+
+	return i.defaultLocale
+}
+
+// CacheVary implements cache.Varier, so cached pages are stored per locale.
+func (i *I18n) CacheVary(r *http.Request) string {
+	return i.Locale(r.Context())
+}
+
+// supported returns the locale, or its language, if there is a catalog for
+// it.
+func (i *I18n) supported(locale string) (string, bool) {
+	if locale == "" {
+		return "", false
+	}
+
+	locale = normalize(locale)
+	for _, candidate := range []string{locale, language(locale)} {
+		if _, ok := i.catalogs[candidate]; ok || candidate == i.defaultLocale {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// SetLocale remembers locale of the visitor in a cookie.
+func SetLocale(w http.ResponseWriter, locale string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    normalize(locale),
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (i *I18n) localizer(locale string) *localizer {
+	return &localizer{
+		locale:  locale,
+		catalog: i.catalogs[locale],
+	}
+}
+
+// Translate implements render.Translator.
+func (i *I18n) Translate(ctx context.Context, msg string) string {
+	return i.localizer(i.Locale(ctx)).T(msg)
+}
+
+// localizer translates messages to one locale.
+type localizer struct {
+	locale  string
+	catalog *catalog
+}
+
+func (l *localizer) T(msg string, args ...interface{}) string {
+	return format(l.catalog.translate(msg), args)
+}
+
+func (l *localizer) N(singular, plural string, n int, args ...interface{}) string {
+	return format(l.catalog.translatePlural(singular, plural, n), args)
+}
+
+func format(msg string, args []interface{}) string {
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// fromI18n returns i18n set with WithLocale or i18n of the request ctx
+// belongs to.
+func fromI18n(ctx context.Context) *I18n {
+	if cl, ok := ctx.Value(localeKey).(contextLocale); ok && cl.i18n != nil {
+		return cl.i18n
+	}
+	if rl, ok := ctx.Value(requestLocaleKey).(requestLocale); ok {
+		return rl.i18n
+	}
+	return nil
+}
+
+func fromContext(ctx context.Context) *localizer {
+	if i := fromI18n(ctx); i != nil {
+		return i.localizer(i.Locale(ctx))
+	}
+	return &localizer{}
+}
+
+// T translates msg to the locale set with WithLocale or the locale of the
+// request ctx belongs to and formats it with args. Msg is returned
+// untranslated when ctx has neither.
+func T(ctx context.Context, msg string, args ...interface{}) string {
+	return fromContext(ctx).T(msg, args...)
+}
+
+// N translates message with plural forms, choosing the form for n.
+func N(ctx context.Context, singular, plural string, n int, args ...interface{}) string {
+	return fromContext(ctx).N(singular, plural, n, args...)
+}
+
+// Locale returns locale set with WithLocale or locale of the request ctx
+// belongs to.
+func Locale(ctx context.Context) string {
+	if i := fromI18n(ctx); i != nil {
+		return i.Locale(ctx)
+	}
+	return ""
+}
+
+// parseAcceptLanguage returns accepted locales ordered by preference.
+func parseAcceptLanguage(header string) []string {
+	var locales []gongo.Accepted
+	for _, accepted := range gongo.ParseAccept(header) {
+		if accepted.Value != "*" && accepted.Q > 0 {
+			locales = append(locales, accepted)
+		}
+	}
+
+	sort.SliceStable(locales, func(i, j int) bool {
+		return locales[i].Q > locales[j].Q
+	})
+
+	result := make([]string, len(locales))
+	for i, accepted := range locales {
+		result[i] = accepted.Value
+	}
+	return result
+}
@@ -0,0 +1,169 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matematik7/gongo/internal/apptest"
+	"github.com/matematik7/gongo/render"
+)
+
+func newApp(t *testing.T, templates fstest.MapFS) (*render.Render, *I18n) {
+	catalogs := fstest.MapFS{
+		"sl.json": {Data: []byte(`{"Hello": "Zdravo", "%d apple": ["%d jabolko", "%d jabolki", "%d jabolka", "%d jabolk"]}`)},
+	}
+
+	r := render.New(false)
+	r.AddTemplates(http.FS(templates))
+	i := New("en", http.FS(catalogs))
+
+	app := apptest.New(r)
+	app.Register("I18n", i)
+	if err := app.Configure(); err != nil {
+		t.Fatal(err)
+	}
+
+	return r, i
+}
+
+func TestTransFilter(t *testing.T) {
+	r, _ := newApp(t, fstest.MapFS{
+		"filter.html": {Data: []byte(`{{ "Hello"|trans }} {{ "Hello"|trans|upper }} {{ "Hello" | trans:i18n }}`)},
+	})
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"sl", "Zdravo ZDRAVO Zdravo"},
+		{"en", "Hello HELLO Hello"},
+	}
+	for _, test := range tests {
+		out, err := r.RenderToString(WithLocale(context.Background(), test.locale), "filter.html", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != test.want {
+			t.Errorf("%s: got %q, want %q", test.locale, out, test.want)
+		}
+	}
+}
+
+func TestBindTransFilter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{{ msg|trans }}`, `{{ msg|trans:i18n }}`},
+		{`{{ msg|trans:other }}`, `{{ msg|trans:other }}`},
+		{`{% if msg|trans %}`, `{% if msg|trans:i18n %}`},
+		{`a|trans {{ transport }}`, `a|trans {{ transport }}`},
+	}
+	for _, test := range tests {
+		if got := string(bindTransFilter([]byte(test.in))); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.in, got, test.want)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"sl", []string{"sl"}},
+		{"en;q=0.5, sl-SI, de;q=0.7", []string{"sl-si", "de", "en"}},
+		{"*, fr;q=0", []string{}},
+	}
+	for _, test := range tests {
+		if got := parseAcceptLanguage(test.header); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.header, got, test.want)
+		}
+	}
+}
+
+func TestLocale(t *testing.T) {
+	_, i := newApp(t, fstest.MapFS{})
+
+	tests := []struct {
+		name   string
+		cookie string
+		accept string
+		want   string
+	}{
+		{"default", "", "", "en"},
+		{"accept language", "", "de, sl-SI;q=0.9", "sl"},
+		{"unsupported", "", "de, fr", "en"},
+		{"cookie", "en", "sl", "en"},
+		{"invalid cookie", "xx", "sl", "sl"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: CookieName, Value: test.cookie})
+		}
+		if test.accept != "" {
+			req.Header.Set("Accept-Language", test.accept)
+		}
+
+		var got string
+		i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = i.Locale(r.Context())
+		})).ServeHTTP(httptest.NewRecorder(), req)
+
+		if got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestWithLocale(t *testing.T) {
+	_, i := newApp(t, fstest.MapFS{})
+
+	ctx := i.WithLocale(context.Background(), "sl-SI")
+	if got := T(ctx, "Hello"); got != "Zdravo" {
+		t.Errorf("T: got %q", got)
+	}
+	if got := Locale(ctx); got != "sl" {
+		t.Errorf("Locale: got %q", got)
+	}
+
+	if got := T(context.Background(), "Hello"); got != "Hello" {
+		t.Errorf("T without locale: got %q", got)
+	}
+	if got := Locale(context.Background()); got != "" {
+		t.Errorf("Locale without locale: got %q", got)
+	}
+
+	// outside of requests WithLocale has no i18n to translate with
+	ctx = WithLocale(context.Background(), "sl")
+	if got := T(ctx, "Hello"); got != "Hello" {
+		t.Errorf("T without i18n: got %q", got)
+	}
+}
+
+func TestN(t *testing.T) {
+	_, i := newApp(t, fstest.MapFS{})
+
+	// WithLocale overrides the locale of the request
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "en")
+
+	var got []string
+	i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithLocale(r.Context(), "sl")
+		for _, n := range []int{1, 2, 3, 5} {
+			got = append(got, N(ctx, "%d apple", "%d apples", n, n))
+		}
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	want := []string{"1 jabolko", "2 jabolki", "3 jabolka", "5 jabolk"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,199 @@
+package i18n
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+
+	"github.com/flosch/pongo2"
+)
+
+func init() {
+	pongo2.RegisterTag("trans", tagTransParser)
+	pongo2.RegisterTag("blocktrans", tagBlockTransParser)
+	pongo2.RegisterFilter("trans", filterTrans)
+}
+
+func contextLocalizer(ctx *pongo2.ExecutionContext) *localizer {
+	if l, ok := ctx.Public[contextKey].(*localizer); ok {
+		return l
+	}
+	return &localizer{}
+}
+
+// lookup resolves variable name like pongo2 does for loop variables and
+// the public context.
+func lookup(ctx *pongo2.ExecutionContext, name string) *pongo2.Value {
+	if v, ok := ctx.Private[name]; ok {
+		return pongo2.AsValue(v)
+	}
+	return pongo2.AsValue(ctx.Public[name])
+}
+
+// {% trans "Hello %s" user.Name %}
+type tagTransNode struct {
+	msg  string
+	args []pongo2.IEvaluator
+}
+
+func (node *tagTransNode) Execute(ctx *pongo2.ExecutionContext, writer pongo2.TemplateWriter) *pongo2.Error {
+	args := make([]interface{}, len(node.args))
+	for i, arg := range node.args {
+		value, err := arg.Evaluate(ctx)
+		if err != nil {
+			return err
+		}
+		if ctx.Autoescape && !value.IsNumber() {
+			args[i] = html.EscapeString(value.String())
+		} else {
+			args[i] = value.Interface()
+		}
+	}
+
+	writer.WriteString(contextLocalizer(ctx).T(node.msg, args...))
+
+	return nil
+}
+
+func tagTransParser(doc *pongo2.Parser, start *pongo2.Token, arguments *pongo2.Parser) (pongo2.INodeTag, *pongo2.Error) {
+	msgToken := arguments.MatchType(pongo2.TokenString)
+	if msgToken == nil {
+		return nil, arguments.Error("Expected a message string.", nil)
+	}
+
+	node := &tagTransNode{
+		msg: msgToken.Val,
+	}
+
+	for arguments.Remaining() > 0 {
+		arg, err := arguments.ParseExpression()
+		if err != nil {
+			return nil, err
+		}
+		node.args = append(node.args, arg)
+	}
+
+	return node, nil
+}
+
+// {{ "Hello"|trans }}
+//
+// Filters do not get the execution context, so bindTransFilter rewrites
+// templates to pass the localizer as the filter parameter.
+func filterTrans(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	l, ok := param.Interface().(*localizer)
+	if !ok {
+		return in, nil
+	}
+	return pongo2.AsValue(l.T(in.String())), nil
+}
+
+var (
+	templateBlock = regexp.MustCompile(`(?s){{.*?}}|{%.*?%}`)
+	transFilter   = regexp.MustCompile(`\|\s*trans\b(\s*:)?`)
+)
+
+// bindTransFilter changes |trans filters without a parameter to
+// |trans:i18n inside of template variables and tags.
+func bindTransFilter(data []byte) []byte {
+	return templateBlock.ReplaceAllFunc(data, func(block []byte) []byte {
+		return transFilter.ReplaceAllFunc(block, func(filter []byte) []byte {
+			if bytes.HasSuffix(filter, []byte(":")) {
+				return filter
+			}
+			return []byte("|trans:" + contextKey)
+		})
+	})
+}
+
+var placeholder = regexp.MustCompile(`%\(([A-Za-z_][A-Za-z0-9_]*)\)s`)
+
+// {% blocktrans count n %}One apple for {{ name }}.{% plural %}{{ n }} apples for {{ name }}.{% endblocktrans %}
+//
+// Message ids contain variables as %(name)s placeholders. Only plain
+// variables are allowed inside the block.
+type tagBlockTransNode struct {
+	singular string
+	plural   string
+	count    string
+}
+
+func (node *tagBlockTransNode) Execute(ctx *pongo2.ExecutionContext, writer pongo2.TemplateWriter) *pongo2.Error {
+	l := contextLocalizer(ctx)
+
+	var msg string
+	if node.count != "" {
+		msg = l.N(node.singular, node.plural, lookup(ctx, node.count).Integer())
+	} else {
+		msg = l.T(node.singular)
+	}
+
+	msg = placeholder.ReplaceAllStringFunc(msg, func(match string) string {
+		value := lookup(ctx, placeholder.FindStringSubmatch(match)[1]).String()
+		if ctx.Autoescape {
+			return html.EscapeString(value)
+		}
+		return value
+	})
+
+	writer.WriteString(msg)
+
+	return nil
+}
+
+func tagBlockTransParser(doc *pongo2.Parser, start *pongo2.Token, arguments *pongo2.Parser) (pongo2.INodeTag, *pongo2.Error) {
+	node := &tagBlockTransNode{}
+
+	if arguments.Match(pongo2.TokenIdentifier, "count") != nil {
+		countToken := arguments.MatchType(pongo2.TokenIdentifier)
+		if countToken == nil {
+			return nil, arguments.Error("Expected a variable name after count.", nil)
+		}
+		node.count = countToken.Val
+	}
+	if arguments.Remaining() > 0 {
+		return nil, arguments.Error("Malformed blocktrans-tag arguments.", nil)
+	}
+
+	msg := &node.singular
+	for {
+		if token := doc.MatchType(pongo2.TokenHTML); token != nil {
+			*msg += token.Val
+			continue
+		}
+
+		if doc.Match(pongo2.TokenSymbol, "{{") != nil {
+			variable := doc.MatchType(pongo2.TokenIdentifier)
+			if variable == nil || doc.Match(pongo2.TokenSymbol, "}}") == nil {
+				return nil, doc.Error("Only plain variables are allowed inside blocktrans.", doc.Current())
+			}
+			*msg += fmt.Sprintf("%%(%s)s", variable.Val)
+			continue
+		}
+
+		if doc.Peek(pongo2.TokenSymbol, "{%") != nil {
+			tag := doc.PeekTypeN(1, pongo2.TokenIdentifier)
+			if tag != nil && doc.PeekN(2, pongo2.TokenSymbol, "%}") != nil {
+				switch {
+				case tag.Val == "plural" && node.count != "" && msg == &node.singular:
+					doc.ConsumeN(3)
+					msg = &node.plural
+					continue
+				case tag.Val == "endblocktrans":
+					doc.ConsumeN(3)
+					if node.count != "" && msg != &node.plural {
+						return nil, doc.Error("blocktrans with count requires a plural block.", tag)
+					}
+					return node, nil
+				}
+			}
+			return nil, doc.Error("Tags are not allowed inside blocktrans.", doc.Current())
+		}
+
+		if doc.Remaining() == 0 {
+			return nil, doc.Error("Unexpected EOF, expected tag endblocktrans.", start)
+		}
+		return nil, doc.Error("Unexpected token inside blocktrans.", doc.Current())
+	}
+}
@@ -0,0 +1,24 @@
+// Package apptest builds apps for tests of components.
+package apptest
+
+import (
+	"github.com/gorilla/sessions"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/logging"
+	"github.com/matematik7/gongo/render"
+)
+
+// New returns app with Logging, Store and Render registered, which most
+// components depend on. Render is created in development mode when r is
+// nil. Components under test are registered before configuring the app.
+func New(r *render.Render) *gongo.App {
+	if r == nil {
+		r = render.New(false)
+	}
+
+	app := gongo.New()
+	app.Register("Logging", logging.New(false))
+	app.Register("Store", sessions.NewCookieStore([]byte("secret")))
+	app.Register("Render", r)
+	return app
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+)
+
+// ConfigureResource adds failed jobs scope and retry action to admin.
+func (j *Jobs) ConfigureResource(model interface{}, res *admin.Resource) {
+	if _, ok := model.(*Job); !ok {
+		return
+	}
+
+	res.IndexAttrs("ID", "Type", "Status", "Attempts", "RunAt", "LastError", "FinishedAt")
+
+	for _, status := range []string{StatusPending, StatusRunning, StatusFailed, StatusDone} {
+		status := status
+		res.Scope(&admin.Scope{
+			Name:  status,
+			Group: "Status",
+			Handler: func(db *gorm.DB, context *qor.Context) *gorm.DB {
+				return db.Where("status = ?", status)
+			},
+		})
+	}
+
+	res.Action(&admin.Action{
+		Name: "Retry",
+		Handler: func(argument *admin.ActionArgument) error {
+			for _, value := range argument.PrimaryValues {
+				id, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					return errors.Wrapf(err, "invalid job id %s", value)
+				}
+				if err := j.Retry(uint(id)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Visible: func(record interface{}, context *admin.Context) bool {
+			job, ok := record.(*Job)
+			return !ok || job.Status == StatusFailed
+		},
+		Modes: []string{"show", "menu_item", "batch"},
+	})
+}
@@ -0,0 +1,427 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/logging"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultWorkers      = 4
+	DefaultPollInterval = 5 * time.Second
+	DefaultMaxAttempts  = 10
+	DefaultBaseDelay    = 10 * time.Second
+	DefaultMaxDelay     = 6 * time.Hour
+	DefaultStaleAfter   = time.Hour
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Options of an enqueued job.
+type Options struct {
+	// RunAt delays the job, it runs as soon as possible by default.
+	RunAt time.Time
+	// UniqueKey skips enqueuing when unfinished job with the same key
+	// exists.
+	UniqueKey string
+	// MaxAttempts overrides Jobs.MaxAttempts.
+	MaxAttempts int
+}
+
+type handler struct {
+	fn          reflect.Value
+	payloadType reflect.Type
+}
+
+type Jobs struct {
+	Workers      int
+	PollInterval time.Duration
+	// MaxAttempts limits runs of failing jobs, retries are delayed
+	// exponentially from BaseDelay up to MaxDelay.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// StaleAfter releases running jobs of workers that died.
+	StaleAfter time.Duration
+
+	db      *gorm.DB
+	logging *logging.Logging
+
+	handlers map[string]handler
+	worker   string
+
+	cancel context.CancelFunc
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func New() *Jobs {
+	return &Jobs{
+		Workers:      DefaultWorkers,
+		PollInterval: DefaultPollInterval,
+		MaxAttempts:  DefaultMaxAttempts,
+		BaseDelay:    DefaultBaseDelay,
+		MaxDelay:     DefaultMaxDelay,
+		StaleAfter:   DefaultStaleAfter,
+		handlers:     make(map[string]handler),
+		worker:       gongo.InstanceID(),
+	}
+}
+
+func (j *Jobs) Dependencies() []string {
+	return []string{"DB", "Logging"}
+}
+
+func (j *Jobs) Configure(app *gongo.App) error {
+	if err := app.Lookup("DB", &j.db); err != nil {
+		return err
+	}
+	if err := app.Lookup("Logging", &j.logging); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (j *Jobs) Resources() []interface{} {
+	return []interface{}{
+		&Job{},
+	}
+}
+
+// Handle registers fn for jobs of jobType. Fn must be a
+// func(context.Context, T) error, where T is the payload type.
+func (j *Jobs) Handle(jobType string, fn interface{}) {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 2 || t.In(0) != contextType || t.NumOut() != 1 || t.Out(0) != errorType {
+		panic(fmt.Sprintf("jobs: handler for %s must be func(context.Context, T) error, got %T", jobType, fn))
+	}
+
+	j.handlers[jobType] = handler{
+		fn:          v,
+		payloadType: t.In(1),
+	}
+}
+
+// Enqueue stores job of jobType with payload, that is encoded as json. If
+// a unfinished job with the same unique key exists, it is returned instead.
+func (j *Jobs) Enqueue(jobType string, payload interface{}, opts Options) (*Job, error) {
+	return j.EnqueueTx(j.db, jobType, payload, opts)
+}
+
+// EnqueueTx enqueues job in transaction tx, so it only runs if tx is
+// committed.
+func (j *Jobs) EnqueueTx(tx *gorm.DB, jobType string, payload interface{}, opts Options) (*Job, error) {
+	if h, ok := j.handlers[jobType]; ok && payload != nil && !assignable(reflect.TypeOf(payload), h.payloadType) {
+		return nil, errors.Errorf("job %s requires payload of type %s, got %T", jobType, h.payloadType, payload)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not encode payload of job %s", jobType)
+	}
+
+	job := &Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      StatusPending,
+		RunAt:       opts.RunAt,
+		MaxAttempts: opts.MaxAttempts,
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = j.MaxAttempts
+	}
+
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+
+		existing := &Job{}
+		query := tx.Where("unique_key = ?", opts.UniqueKey).First(existing)
+		if query.Error == nil {
+			return existing, nil
+		} else if !query.RecordNotFound() {
+			return nil, errors.Wrapf(query.Error, "could not check unique job %s", opts.UniqueKey)
+		}
+	}
+
+	if err := tx.Create(job).Error; err != nil {
+		if opts.UniqueKey != "" {
+			// job with the same key was enqueued concurrently
+			existing := &Job{}
+			if tx.Where("unique_key = ?", opts.UniqueKey).First(existing).Error == nil {
+				return existing, nil
+			}
+		}
+		return nil, errors.Wrapf(err, "could not enqueue job %s", jobType)
+	}
+
+	return job, nil
+}
+
+// assignable reports whether payload of type t can be decoded into handler
+// payload type, pointers are encoded the same as values.
+func assignable(t, payloadType reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if payloadType.Kind() == reflect.Ptr {
+		payloadType = payloadType.Elem()
+	}
+	return t.AssignableTo(payloadType)
+}
+
+// Retry runs failed job again.
+func (j *Jobs) Retry(id uint) error {
+	err := j.db.Model(&Job{}).Where("id = ? AND status = ?", id, StatusFailed).Updates(map[string]interface{}{
+		"status":      StatusPending,
+		"attempts":    0,
+		"run_at":      time.Now(),
+		"finished_at": nil,
+	}).Error
+	if err != nil {
+		return errors.Wrapf(err, "could not retry job %d", id)
+	}
+
+	return nil
+}
+
+func (j *Jobs) Start(ctx context.Context) error {
+	for jobType := range j.handlers {
+		j.logging.WithContext(ctx).WithField("Type", jobType).Debug("job handler registered")
+	}
+
+	var workCtx context.Context
+	workCtx, j.cancel = context.WithCancel(context.Background())
+	j.stop = make(chan struct{})
+
+	for i := 0; i < j.Workers; i++ {
+		j.wg.Add(1)
+		go j.work(workCtx, fmt.Sprintf("%s-%d", j.worker, i))
+	}
+
+	return nil
+}
+
+// Stop waits for running jobs to finish, until ctx is done, when their
+// context is canceled. Jobs that ignore the cancellation are released as
+// stale by other workers.
+func (j *Jobs) Stop(ctx context.Context) error {
+	close(j.stop)
+
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		j.cancel()
+		return nil
+	case <-ctx.Done():
+		j.cancel()
+		return errors.New("running jobs were canceled")
+	}
+}
+
+func (j *Jobs) work(ctx context.Context, worker string) {
+	defer j.wg.Done()
+
+	// workers poll at different times
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(j.PollInterval) + 1)))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-j.stop:
+			return
+		case <-timer.C:
+		}
+
+		for {
+			select {
+			case <-j.stop:
+				return
+			default:
+			}
+
+			job, err := j.claim(worker)
+			if err != nil {
+				j.logging.WithContext(ctx).WithError(err).Error("could not claim job")
+				break
+			}
+			if job == nil {
+				break
+			}
+			j.run(ctx, job)
+		}
+
+		timer.Reset(j.PollInterval)
+	}
+}
+
+// claim marks the next due job as running by worker, it returns nil if
+// there is none.
+func (j *Jobs) claim(worker string) (*Job, error) {
+	now := time.Now()
+
+	if err := j.releaseStale(now); err != nil {
+		return nil, err
+	}
+
+	types := make([]string, 0, len(j.handlers))
+	for jobType := range j.handlers {
+		types = append(types, jobType)
+	}
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	for {
+		job := &Job{}
+		query := j.db.Where("status = ? AND run_at <= ? AND type IN (?)", StatusPending, now, types).Order("run_at").First(job)
+		if query.RecordNotFound() {
+			return nil, nil
+		} else if query.Error != nil {
+			return nil, errors.Wrap(query.Error, "could not find job")
+		}
+
+		// another worker can claim the job first
+		update := j.db.Model(&Job{}).Where("id = ? AND status = ?", job.ID, StatusPending).Updates(map[string]interface{}{
+			"status":    StatusRunning,
+			"locked_at": now,
+			"locked_by": worker,
+		})
+		if update.Error != nil {
+			return nil, errors.Wrapf(update.Error, "could not claim job %d", job.ID)
+		}
+		if update.RowsAffected == 1 {
+			job.Status = StatusRunning
+			job.LockedAt = &now
+			job.LockedBy = worker
+			return job, nil
+		}
+	}
+}
+
+// releaseStale counts runs of jobs whose worker died as failed attempts,
+// so jobs that crash the worker are not retried forever.
+func (j *Jobs) releaseStale(now time.Time) error {
+	const staleError = "worker stopped while running the job"
+	stale := j.db.Model(&Job{}).Where("status = ? AND locked_at < ?", StatusRunning, now.Add(-j.StaleAfter))
+
+	err := stale.Where("attempts + 1 >= max_attempts").Updates(map[string]interface{}{
+		"status":      StatusFailed,
+		"attempts":    gorm.Expr("attempts + 1"),
+		"finished_at": now,
+		"unique_key":  nil,
+		"last_error":  staleError,
+		"locked_at":   nil,
+		"locked_by":   "",
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "could not fail stale jobs")
+	}
+
+	err = stale.Updates(map[string]interface{}{
+		"status":     StatusPending,
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": staleError,
+		"locked_at":  nil,
+		"locked_by":  "",
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "could not release stale jobs")
+	}
+
+	return nil
+}
+
+func (j *Jobs) run(ctx context.Context, job *Job) {
+	ctx = logging.NewContext(ctx, map[string]interface{}{
+		"JobID":   job.ID,
+		"Type":    job.Type,
+		"Attempt": job.Attempts + 1,
+	})
+
+	start := time.Now()
+	err := j.call(ctx, job)
+	// fields added by the handler are logged too
+	log := j.logging.WithContext(ctx).WithField("Latency", time.Since(start).String())
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"attempts":  job.Attempts + 1,
+		"locked_at": nil,
+		"locked_by": "",
+	}
+
+	if err == nil {
+		updates["status"] = StatusDone
+		updates["finished_at"] = now
+		updates["unique_key"] = nil
+		log.Info("job done")
+	} else if job.Attempts+1 >= job.MaxAttempts {
+		updates["status"] = StatusFailed
+		updates["finished_at"] = now
+		updates["unique_key"] = nil
+		updates["last_error"] = err.Error()
+		log.WithError(err).Error("job failed")
+	} else {
+		updates["status"] = StatusPending
+		updates["run_at"] = now.Add(j.backoff(job.Attempts + 1))
+		updates["last_error"] = err.Error()
+		log.WithError(err).Warn("job failed, retrying")
+	}
+
+	if err := j.db.Model(&Job{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+		log.WithError(err).Error("could not update job")
+	}
+}
+
+func (j *Jobs) call(ctx context.Context, job *Job) error {
+	h, ok := j.handlers[job.Type]
+	if !ok {
+		return errors.Errorf("no handler for job %s", job.Type)
+	}
+
+	payload := reflect.New(h.payloadType)
+	if err := json.Unmarshal([]byte(job.Payload), payload.Interface()); err != nil {
+		return errors.Wrap(err, "could not decode payload")
+	}
+
+	return gongo.SafeCall(func() error {
+		out := h.fn.Call([]reflect.Value{reflect.ValueOf(ctx), payload.Elem()})
+		if err, ok := out[0].Interface().(error); ok {
+			return err
+		}
+		return nil
+	})
+}
+
+// backoff returns delay before attempt, doubling BaseDelay with 10% jitter.
+func (j *Jobs) backoff(attempt int) time.Duration {
+	delay := j.MaxDelay
+	if shift := uint(attempt - 1); shift < 32 {
+		if d := j.BaseDelay << shift; d > 0 && d < j.MaxDelay {
+			delay = d
+		}
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1))
+}
@@ -0,0 +1,284 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/matematik7/gongo/logging"
+	"github.com/pkg/errors"
+)
+
+type payload struct {
+	Name string
+}
+
+func newJobs(t *testing.T) *Jobs {
+	DB, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DB.Close() })
+	// every connection to :memory: is a new database
+	DB.DB().SetMaxOpenConns(1)
+
+	if err := DB.AutoMigrate(&Job{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	j := New()
+	j.db = DB
+	j.logging = logging.New(false)
+
+	return j
+}
+
+func find(t *testing.T, j *Jobs, id uint) *Job {
+	job := &Job{}
+	if err := j.db.First(job, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestEnqueueUnique(t *testing.T) {
+	j := newJobs(t)
+	j.Handle("greet", func(ctx context.Context, p payload) error {
+		return nil
+	})
+
+	first, err := j.Enqueue("greet", payload{Name: "ana"}, Options{UniqueKey: "greet-ana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := j.Enqueue("greet", payload{Name: "ana"}, Options{UniqueKey: "greet-ana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != second.ID {
+		t.Errorf("unique job was enqueued twice, got %d and %d", first.ID, second.ID)
+	}
+
+	// the key is released when the job is done
+	job, err := j.claim("worker")
+	if err != nil || job == nil {
+		t.Fatalf("got job %v and error %v", job, err)
+	}
+	j.run(context.Background(), job)
+
+	third, err := j.Enqueue("greet", payload{Name: "ana"}, Options{UniqueKey: "greet-ana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.ID == first.ID {
+		t.Error("finished unique job was returned")
+	}
+
+	if _, err := j.Enqueue("greet", "ana", Options{}); err == nil {
+		t.Error("payload of wrong type was enqueued")
+	}
+}
+
+func TestClaim(t *testing.T) {
+	j := newJobs(t)
+	j.Handle("greet", func(ctx context.Context, p payload) error {
+		return nil
+	})
+
+	if _, err := j.Enqueue("greet", payload{}, Options{RunAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Enqueue("unknown", payload{}, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	due, err := j.Enqueue("greet", payload{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := j.claim("worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job == nil || job.ID != due.ID {
+		t.Fatalf("got %+v, want job %d", job, due.ID)
+	}
+	if stored := find(t, j, job.ID); stored.Status != StatusRunning || stored.LockedBy != "worker" {
+		t.Errorf("got status %s locked by %q", stored.Status, stored.LockedBy)
+	}
+
+	// delayed jobs, jobs without handlers and running jobs are not claimed
+	job, err = j.claim("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job != nil {
+		t.Errorf("claimed job %+v", job)
+	}
+}
+
+func TestRunRetry(t *testing.T) {
+	j := newJobs(t)
+	j.BaseDelay = time.Minute
+	j.Handle("fail", func(ctx context.Context, p payload) error {
+		return errors.New("boom")
+	})
+
+	enqueued, err := j.Enqueue("fail", payload{}, Options{MaxAttempts: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, _ := j.claim("worker")
+	j.run(context.Background(), job)
+
+	stored := find(t, j, enqueued.ID)
+	if stored.Status != StatusPending || stored.Attempts != 1 || stored.LastError != "boom" {
+		t.Errorf("first attempt: got %+v", stored)
+	}
+	if !stored.RunAt.After(time.Now().Add(50 * time.Second)) {
+		t.Errorf("retry was not delayed, runs at %s", stored.RunAt)
+	}
+	if job, _ := j.claim("worker"); job != nil {
+		t.Error("delayed retry was claimed")
+	}
+
+	j.run(context.Background(), stored)
+
+	stored = find(t, j, enqueued.ID)
+	if stored.Status != StatusFailed || stored.Attempts != 2 || stored.FinishedAt == nil {
+		t.Errorf("last attempt: got %+v", stored)
+	}
+
+	if err := j.Retry(stored.ID); err != nil {
+		t.Fatal(err)
+	}
+	if stored = find(t, j, enqueued.ID); stored.Status != StatusPending || stored.Attempts != 0 {
+		t.Errorf("retry: got %+v", stored)
+	}
+}
+
+func TestRunPanic(t *testing.T) {
+	j := newJobs(t)
+	j.Handle("panic", func(ctx context.Context, p payload) error {
+		panic("crashed " + p.Name)
+	})
+
+	enqueued, err := j.Enqueue("panic", payload{Name: "ana"}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, _ := j.claim("worker")
+	j.run(context.Background(), job)
+
+	stored := find(t, j, enqueued.ID)
+	if stored.Status != StatusPending || stored.Attempts != 1 || !strings.Contains(stored.LastError, "crashed ana") {
+		t.Errorf("got %+v", stored)
+	}
+}
+
+func TestReleaseStale(t *testing.T) {
+	j := newJobs(t)
+	j.Handle("crash", func(ctx context.Context, p payload) error {
+		return nil
+	})
+
+	retried, err := j.Enqueue("crash", payload{}, Options{MaxAttempts: 3, UniqueKey: "retried"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := j.Enqueue("crash", payload{}, Options{MaxAttempts: 1, UniqueKey: "failed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both jobs were claimed by workers that died
+	locked := time.Now().Add(-2 * j.StaleAfter)
+	err = j.db.Model(&Job{}).Updates(map[string]interface{}{
+		"status":    StatusRunning,
+		"locked_at": locked,
+		"locked_by": "dead",
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.releaseStale(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := find(t, j, retried.ID)
+	if stored.Status != StatusPending || stored.Attempts != 1 || stored.LockedBy != "" {
+		t.Errorf("retried: got %+v", stored)
+	}
+	stored = find(t, j, failed.ID)
+	if stored.Status != StatusFailed || stored.Attempts != 1 || stored.UniqueKey != nil || stored.FinishedAt == nil {
+		t.Errorf("failed: got %+v", stored)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	j := New()
+	j.BaseDelay = time.Second
+	j.MaxDelay = 10 * time.Second
+
+	tests := []struct {
+		attempt int
+		min     time.Duration
+	}{
+		{1, time.Second},
+		{3, 4 * time.Second},
+		{5, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, test := range tests {
+		delay := j.backoff(test.attempt)
+		if delay < test.min || delay > test.min+test.min/10 {
+			t.Errorf("attempt %d: got %s, want %s with 10%% jitter", test.attempt, delay, test.min)
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	j := newJobs(t)
+	j.Workers = 1
+	j.PollInterval = time.Millisecond
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	// the handler ignores cancellation of its context
+	j.Handle("block", func(ctx context.Context, p payload) error {
+		close(started)
+		<-release
+		return nil
+	})
+	if _, err := j.Enqueue("block", payload{}, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- j.Stop(ctx)
+	}()
+	select {
+	case err := <-stopped:
+		if err == nil {
+			t.Error("Stop returned nil with a running job")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return when ctx was done")
+	}
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import "time"
+
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+type Job struct {
+	ID        uint `gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Type    string `gorm:"index"`
+	Payload string `gorm:"type:text"`
+	// UniqueKey prevents enqueuing the same job twice, it is cleared when
+	// the job is finished
+	UniqueKey *string `gorm:"unique_index"`
+
+	Status      string    `gorm:"index"`
+	RunAt       time.Time `gorm:"index"`
+	Attempts    int
+	MaxAttempts int
+	LastError   string `gorm:"type:text"`
+
+	LockedAt   *time.Time
+	LockedBy   string
+	FinishedAt *time.Time
+}
@@ -0,0 +1,99 @@
+package gongo
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Starter is implemented by components that run background work after the
+// whole app is configured. Start should not block.
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+// Stopper is implemented by components that need to clean up on exit.
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+const DefaultStopTimeout = 30 * time.Second
+
+// Run configures the app if needed, starts components in dependency order
+// and blocks until ctx is done or SIGINT/SIGTERM is received. Started
+// components are then stopped in reverse order within StopTimeout.
+func (app *App) Run(ctx context.Context) error {
+	if !app.configured {
+		if err := app.Configure(); err != nil {
+			return err
+		}
+	}
+
+	order, err := app.Order()
+	if err != nil {
+		return errors.Wrap(err, "could not determine start order")
+	}
+
+	// signals received while starting are handled once all components
+	// are started, so the started ones are stopped too
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	started := make([]string, 0, len(order))
+	for _, name := range order {
+		if starter, ok := app.components[name].(Starter); ok {
+			if err := starter.Start(ctx); err != nil {
+				startErr := errors.Wrapf(err, "could not start %s", name)
+				if stopErr := app.stop(started); stopErr != nil {
+					return MultiError{startErr, stopErr}
+				}
+				return startErr
+			}
+		}
+		started = append(started, name)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-signals:
+	}
+
+	return app.stop(started)
+}
+
+func (app *App) stop(names []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), app.StopTimeout)
+	defer cancel()
+
+	var errs MultiError
+	for i := len(names) - 1; i >= 0; i-- {
+		if stopper, ok := app.components[names[i]].(Stopper); ok {
+			if err := stopWithin(ctx, stopper); err != nil {
+				errs = append(errs, errors.Wrapf(err, "could not stop %s", names[i]))
+			}
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// stopWithin stops stopper, but gives up when ctx is done, so a stopper that
+// ignores ctx does not block Run forever.
+func stopWithin(ctx context.Context, stopper Stopper) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- stopper.Stop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "stop timed out")
+	}
+}
@@ -0,0 +1,119 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = append(e.list, event)
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string{}, e.list...)
+}
+
+type lifecycleComponent struct {
+	name         string
+	dependencies []string
+	events       *events
+	startErr     error
+	block        chan struct{}
+}
+
+func (c *lifecycleComponent) Dependencies() []string {
+	return c.dependencies
+}
+
+func (c *lifecycleComponent) Start(ctx context.Context) error {
+	c.events.add("start " + c.name)
+	return c.startErr
+}
+
+func (c *lifecycleComponent) Stop(ctx context.Context) error {
+	c.events.add("stop " + c.name)
+	if c.block != nil {
+		// ignores ctx
+		<-c.block
+	}
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	e := &events{}
+	app := New()
+	app.Register("Server", &lifecycleComponent{name: "Server", dependencies: []string{"DB"}, events: e})
+	app.Register("DB", &lifecycleComponent{name: "DB", events: e})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := app.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"start DB", "start Server", "stop Server", "stop DB"}
+	if got := e.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRunStartError(t *testing.T) {
+	e := &events{}
+	app := New()
+	app.Register("DB", &lifecycleComponent{name: "DB", events: e})
+	app.Register("Server", &lifecycleComponent{name: "Server", events: e, startErr: errors.New("port in use")})
+	app.Register("Jobs", &lifecycleComponent{name: "Jobs", events: e})
+
+	err := app.Run(context.Background())
+	if err == nil || err.Error() != "could not start Server: port in use" {
+		t.Errorf("got %v", err)
+	}
+
+	// only components started before the failing one are stopped
+	want := []string{"start DB", "start Server", "stop DB"}
+	if got := e.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRunStopTimeout(t *testing.T) {
+	e := &events{}
+	block := make(chan struct{})
+	defer close(block)
+
+	app := New()
+	app.StopTimeout = 10 * time.Millisecond
+	app.Register("DB", &lifecycleComponent{name: "DB", events: e})
+	app.Register("Mail", &lifecycleComponent{name: "Mail", events: e, block: block})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.HasPrefix(err.Error(), "could not stop Mail: stop timed out") {
+			t.Errorf("got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after StopTimeout")
+	}
+}
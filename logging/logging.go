@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/matematik7/gongo"
+	"github.com/sirupsen/logrus"
+)
+
+type key int
+
+const requestLoggerKey key = iota
+
+type FieldsProvider interface {
+	LoggerFields(context.Context) map[string]interface{}
+}
+
+type Logging struct {
+	log             *logrus.Logger
+	fieldsProviders []FieldsProvider
+}
+
+func New(isProd bool) *Logging {
+	log := logrus.New()
+	if isProd {
+		log.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		log.SetLevel(logrus.DebugLevel)
+	}
+
+	return &Logging{
+		log: log,
+	}
+}
+
+func (l *Logging) Configure(app *gongo.App) error {
+	for _, name := range app.Names() {
+		if fieldsProvider, ok := app.Get(name).(FieldsProvider); ok {
+			l.fieldsProviders = append(l.fieldsProviders, fieldsProvider)
+		}
+	}
+
+	return nil
+}
+
+func (l *Logging) Logger() *logrus.Logger {
+	return l.log
+}
+
+// WithContext returns logger with fields of the request ctx belongs to and
+// fields from all FieldsProviders.
+func (l *Logging) WithContext(ctx context.Context) *logrus.Entry {
+	fields := logrus.Fields{}
+	if rl, ok := ctx.Value(requestLoggerKey).(*requestLogger); ok {
+		rl.mutex.Lock()
+		for k, v := range rl.fields {
+			fields[k] = v
+		}
+		rl.mutex.Unlock()
+	} else if reqID := middleware.GetReqID(ctx); reqID != "" {
+		fields["RequestId"] = reqID
+	}
+
+	for _, fieldsProvider := range l.fieldsProviders {
+		for k, v := range fieldsProvider.LoggerFields(ctx) {
+			fields[k] = v
+		}
+	}
+
+	return l.log.WithFields(fields)
+}
+
+func (l *Logging) WithRequest(r *http.Request) *logrus.Entry {
+	return l.WithContext(r.Context()).WithField("URL", r.URL.String())
+}
+
+// Middleware attaches request logger to the request context and logs one
+// line per request once it is served.
+func (l *Logging) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		ctx := NewContext(r.Context(), map[string]interface{}{
+			"RequestId": middleware.GetReqID(r.Context()),
+			"Method":    r.Method,
+			"URL":       r.URL.String(),
+		})
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		// deferred, so requests that panic are logged too
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			l.WithContext(ctx).WithFields(logrus.Fields{
+				"Status":  status,
+				"Latency": time.Since(start).String(),
+				"Bytes":   ww.BytesWritten(),
+			}).Info("request served")
+		}()
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+	})
+}
+
+type requestLogger struct {
+	mutex  sync.Mutex
+	fields logrus.Fields
+}
+
+// NewContext returns ctx with a logger holding fields and fields of the
+// logger of ctx. Work outside of requests, like jobs, uses it so it is
+// logged with WithContext and AddFields like requests.
+func NewContext(ctx context.Context, fields map[string]interface{}) context.Context {
+	rl := &requestLogger{
+		fields: logrus.Fields{},
+	}
+	if parent, ok := ctx.Value(requestLoggerKey).(*requestLogger); ok {
+		parent.mutex.Lock()
+		for k, v := range parent.fields {
+			rl.fields[k] = v
+		}
+		parent.mutex.Unlock()
+	}
+	for k, v := range fields {
+		rl.fields[k] = v
+	}
+
+	return context.WithValue(ctx, requestLoggerKey, rl)
+}
+
+// AddFields adds fields to the request logger, so they are included in
+// every following log line and in the access log. Use it for values that
+// are only known deeper in the middleware chain, such as the user.
+func AddFields(ctx context.Context, fields map[string]interface{}) {
+	rl, ok := ctx.Value(requestLoggerKey).(*requestLogger)
+	if !ok {
+		return
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	for k, v := range fields {
+		rl.fields[k] = v
+	}
+}
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/matematik7/gongo"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type userFields struct{}
+
+func (userFields) LoggerFields(ctx context.Context) map[string]interface{} {
+	return map[string]interface{}{"Provider": "user"}
+}
+
+func newLogging(t *testing.T) (*Logging, *test.Hook) {
+	l := New(false)
+	l.log.SetOutput(ioutil.Discard)
+	hook := test.NewLocal(l.log)
+
+	app := gongo.New()
+	app.Register("Logging", l)
+	app.Register("Fields", userFields{})
+	if err := app.Configure(); err != nil {
+		t.Fatal(err)
+	}
+
+	return l, hook
+}
+
+func TestMiddleware(t *testing.T) {
+	l, hook := newLogging(t)
+
+	handler := middleware.RequestID(l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddFields(r.Context(), map[string]interface{}{"User": 7})
+		l.WithRequest(r).Info("handling")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/notes?draft=1", nil))
+
+	entries := hook.AllEntries()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	handling, served := entries[0], entries[1]
+
+	for _, entry := range entries {
+		if entry.Data["RequestId"] == "" || entry.Data["RequestId"] == nil {
+			t.Errorf("%s: missing RequestId", entry.Message)
+		}
+		if entry.Data["User"] != 7 || entry.Data["Provider"] != "user" || entry.Data["URL"] != "/notes?draft=1" {
+			t.Errorf("%s: got fields %v", entry.Message, entry.Data)
+		}
+	}
+
+	if handling.Message != "handling" {
+		t.Errorf("got message %q", handling.Message)
+	}
+	if served.Message != "request served" || served.Data["Method"] != "POST" || served.Data["Status"] != http.StatusCreated || served.Data["Bytes"] != 7 {
+		t.Errorf("access log: got %q with %v", served.Message, served.Data)
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	l, hook := newLogging(t)
+
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	func() {
+		defer func() {
+			recover()
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	if entry := hook.LastEntry(); entry == nil || entry.Message != "request served" {
+		t.Errorf("request that panicked was not logged: %v", entry)
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	l, hook := newLogging(t)
+
+	ctx := NewContext(context.Background(), map[string]interface{}{"Job": 1})
+	child := NewContext(ctx, map[string]interface{}{"Attempt": 2})
+	AddFields(child, map[string]interface{}{"User": 3})
+
+	l.WithContext(child).Info("child")
+	l.WithContext(ctx).Info("parent")
+
+	entries := hook.AllEntries()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	want := []logrus.Fields{
+		{"Job": 1, "Attempt": 2, "User": 3, "Provider": "user"},
+		{"Job": 1, "Provider": "user"},
+	}
+	for i, entry := range entries {
+		if len(entry.Data) != len(want[i]) {
+			t.Errorf("%s: got fields %v, want %v", entry.Message, entry.Data, want[i])
+			continue
+		}
+		for k, v := range want[i] {
+			if entry.Data[k] != v {
+				t.Errorf("%s: got fields %v, want %v", entry.Message, entry.Data, want[i])
+			}
+		}
+	}
+}
+
+func TestAddFieldsWithoutLogger(t *testing.T) {
+	l, hook := newLogging(t)
+
+	// does nothing outside of requests
+	AddFields(context.Background(), map[string]interface{}{"User": 3})
+	l.WithContext(context.Background()).Info("outside")
+
+	if entry := hook.LastEntry(); entry.Data["User"] != nil {
+		t.Errorf("got fields %v", entry.Data)
+	}
+}
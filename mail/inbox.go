@@ -0,0 +1,228 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+)
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// DevInbox shows messages kept by the transport of Mail, register it only
+// in development.
+type DevInbox struct {
+	prefix string
+
+	inbox  Inbox
+	render *render.Render
+}
+
+func NewDevInbox(urlPrefix string) *DevInbox {
+	return &DevInbox{
+		prefix: urlPrefix,
+	}
+}
+
+func (di *DevInbox) Dependencies() []string {
+	return []string{"Mail", "Render"}
+}
+
+func (di *DevInbox) Configure(app *gongo.App) error {
+	var m *Mail
+	if err := app.Lookup("Mail", &m); err != nil {
+		return err
+	}
+	if err := app.Lookup("Render", &di.render); err != nil {
+		return err
+	}
+
+	inbox, ok := m.Transport().(Inbox)
+	if !ok {
+		return errors.Errorf("mail transport %T does not keep sent mails", m.Transport())
+	}
+	di.inbox = inbox
+
+	templates, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return errors.Wrap(err, "could not load default templates")
+	}
+	di.render.AddDefaultTemplates(http.FS(templates))
+
+	return nil
+}
+
+func (di *DevInbox) Prefix() string {
+	return di.prefix
+}
+
+func (di *DevInbox) ServeMux() http.Handler {
+	router := chi.NewRouter()
+
+	router.Get("/", di.render.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		sent, err := di.inbox.Sent()
+		if err != nil {
+			return err
+		}
+
+		di.render.Template(w, r, "mail/inbox.html", render.Context{
+			"prefix": gongo.PathPrefix(di.prefix),
+			"mails":  sent,
+		})
+		return nil
+	}))
+
+	router.Get("/{id}", di.render.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		s, err := di.find(chi.URLParam(r, "id"))
+		if err != nil {
+			return err
+		}
+
+		content, err := parseContent(s.Raw)
+		if err != nil {
+			return err
+		}
+
+		di.render.Template(w, r, "mail/message.html", render.Context{
+			"prefix":  gongo.PathPrefix(di.prefix),
+			"mail":    s,
+			"content": content,
+		})
+		return nil
+	}))
+
+	router.Get("/{id}/html", di.render.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		s, err := di.find(chi.URLParam(r, "id"))
+		if err != nil {
+			return err
+		}
+
+		content, err := parseContent(s.Raw)
+		if err != nil {
+			return err
+		}
+		if content.HTML == "" {
+			return render.NotFoundError{}
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, err = io.WriteString(w, content.HTML)
+		return err
+	}))
+
+	router.Get("/{id}/raw", di.render.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		s, err := di.find(chi.URLParam(r, "id"))
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "message/rfc822")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": s.ID + ".eml"}))
+		_, err = w.Write(s.Raw)
+		return err
+	}))
+
+	return router
+}
+
+func (di *DevInbox) find(id string) (Sent, error) {
+	sent, err := di.inbox.Sent()
+	if err != nil {
+		return Sent{}, err
+	}
+	for _, s := range sent {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return Sent{}, render.NotFoundError{}
+}
+
+// content is decoded body of a sent message.
+type content struct {
+	Header      mail.Header
+	Text        string
+	HTML        string
+	Attachments []string
+}
+
+func parseContent(raw []byte) (*content, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse mail")
+	}
+
+	c := &content{
+		Header: msg.Header,
+	}
+	err = c.addPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), "", msg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *content) addPart(contentType, encoding, disposition string, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return errors.Wrap(err, "could not read mail part")
+			}
+			err = c.addPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part.Header.Get("Content-Disposition"), part)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if strings.HasPrefix(disposition, "attachment") {
+		_, dispositionParams, _ := mime.ParseMediaType(disposition)
+		c.Attachments = append(c.Attachments, dispositionParams["filename"])
+		return nil
+	}
+
+	switch strings.ToLower(encoding) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return errors.Wrap(err, "could not decode mail part")
+	}
+
+	switch mediaType {
+	case "text/plain":
+		c.Text = string(data)
+	case "text/html":
+		c.HTML = string(data)
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package mail
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/logging"
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultQueueSize  = 100
+	DefaultRetries    = 5
+	DefaultRetryDelay = 10 * time.Second
+)
+
+type queued struct {
+	msg     *Message
+	attempt int
+}
+
+type Mail struct {
+	// Retries limits how many times queued messages are resent, the delay
+	// between retries starts at RetryDelay and doubles every time.
+	Retries    int
+	RetryDelay time.Duration
+
+	from      string
+	transport Transport
+
+	logging *logging.Logging
+	render  *render.Render
+
+	queue chan queued
+	stop  chan struct{}
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// New creates mail component sending with transport, from is the sender
+// of messages without one.
+func New(transport Transport, from string) *Mail {
+	return &Mail{
+		Retries:    DefaultRetries,
+		RetryDelay: DefaultRetryDelay,
+		from:       from,
+		transport:  transport,
+		queue:      make(chan queued, DefaultQueueSize),
+	}
+}
+
+func (m *Mail) Dependencies() []string {
+	return []string{"Logging", "Render"}
+}
+
+func (m *Mail) Configure(app *gongo.App) error {
+	if err := app.Lookup("Logging", &m.logging); err != nil {
+		return err
+	}
+	if err := app.Lookup("Render", &m.render); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Mail) Transport() Transport {
+	return m.transport
+}
+
+// NewMessage renders message to recipients from email templates name, see
+// Render.RenderEmail. Use (*i18n.I18n).WithLocale on ctx to choose the
+// locale.
+func (m *Mail) NewMessage(ctx context.Context, name string, tctx render.Context, to ...string) (*Message, error) {
+	email, err := m.render.RenderEmail(ctx, name, tctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not render mail %s", name)
+	}
+
+	return &Message{
+		From:    m.from,
+		To:      to,
+		Subject: email.Subject,
+		Text:    email.Text,
+		HTML:    email.HTML,
+	}, nil
+}
+
+// Send sends msg immediately.
+func (m *Mail) Send(ctx context.Context, msg *Message) error {
+	if msg.From == "" {
+		msg.From = m.from
+	}
+	if err := msg.Validate(); err != nil {
+		return errors.Wrap(err, "invalid message")
+	}
+	return m.transport.Send(ctx, msg)
+}
+
+// Queue sends msg in the background and retries on failure. Messages are
+// sent only after the app is started. The queue and retries are kept in
+// memory, so messages not sent before the process exits are lost, send
+// them from a job when they must be delivered.
+func (m *Mail) Queue(msg *Message) error {
+	if msg.From == "" {
+		msg.From = m.from
+	}
+	if err := msg.Validate(); err != nil {
+		return errors.Wrap(err, "invalid message")
+	}
+
+	select {
+	case m.queue <- queued{msg: msg}:
+		return nil
+	default:
+		return errors.New("mail queue is full")
+	}
+}
+
+func (m *Mail) Start(ctx context.Context) error {
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+		for {
+			select {
+			case <-m.stop:
+				return
+			case q := <-m.queue:
+				m.send(q)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (m *Mail) send(q queued) {
+	err := m.transport.Send(context.Background(), q.msg)
+	if err == nil {
+		return
+	}
+
+	log := m.logging.Logger().WithError(err).WithFields(map[string]interface{}{
+		"To":      q.msg.To,
+		"Subject": q.msg.Subject,
+		"Attempt": q.attempt + 1,
+	})
+	if q.attempt >= m.Retries {
+		log.Error("could not send mail, giving up")
+		return
+	}
+	log.Warn("could not send mail, retrying")
+
+	q.attempt++
+	delay := m.RetryDelay << uint(q.attempt-1)
+
+	m.wg.Add(1)
+	timer := time.NewTimer(delay)
+	go func() {
+		defer m.wg.Done()
+		select {
+		case <-timer.C:
+		case <-m.stop:
+			timer.Stop()
+		}
+		// after stop the message stays in the queue and is sent by Stop
+		select {
+		case m.queue <- q:
+		default:
+			log.Error("could not retry mail, queue is full")
+		}
+	}()
+}
+
+// Stop stops the background worker and sends remaining messages once,
+// until ctx is done.
+func (m *Mail) Stop(ctx context.Context) error {
+	close(m.stop)
+
+	// retries waiting for their delay are queued when they see stop
+	stopped := make(chan struct{})
+	go func() {
+		<-m.done
+		m.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "could not stop mail worker")
+	}
+
+	for {
+		select {
+		case q := <-m.queue:
+			if err := m.transport.Send(ctx, q.msg); err != nil {
+				m.logging.Logger().WithError(err).WithField("To", q.msg.To).Error("could not send mail")
+			}
+		case <-ctx.Done():
+			return errors.Errorf("%d mails were not sent", len(m.queue))
+		default:
+			return nil
+		}
+	}
+}
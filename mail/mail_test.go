@@ -0,0 +1,113 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/matematik7/gongo/logging"
+)
+
+// blocking transport sends nothing until release is closed.
+type blocking struct {
+	release chan struct{}
+}
+
+func (t *blocking) Send(ctx context.Context, msg *Message) error {
+	<-t.release
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	transport := NewMemory()
+	m := New(transport, "shop@example.com")
+	m.logging = logging.New(false)
+
+	if err := m.Queue(&Message{To: []string{"ana@example.com"}, Subject: "Hello", Text: "Hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	transport.AssertSent(t, "ana@example.com", "Hello")
+	transport.AssertCount(t, 1)
+}
+
+func TestStopTimeout(t *testing.T) {
+	transport := &blocking{release: make(chan struct{})}
+	defer close(transport.release)
+
+	m := New(transport, "shop@example.com")
+	m.logging = logging.New(false)
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Queue(&Message{To: []string{"ana@example.com"}, Subject: "Hello", Text: "Hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait for the worker to take the message
+	for len(m.queue) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- m.Stop(ctx)
+	}()
+	select {
+	case err := <-stopped:
+		if err == nil {
+			t.Error("Stop returned nil while sending")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return when ctx was done")
+	}
+}
+
+func TestSMTPContext(t *testing.T) {
+	// the server accepts connections, but never greets the client
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				break
+			}
+			conns = append(conns, conn)
+		}
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	transport, err := NewSMTP(listener.Addr().String(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = transport.Send(ctx, &Message{From: "shop@example.com", To: []string{"ana@example.com"}, Subject: "Hello", Text: "Hi"})
+	if err == nil {
+		t.Fatal("Send succeeded without a server")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send returned after %s", elapsed)
+	}
+}
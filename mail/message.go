@@ -0,0 +1,310 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/matematik7/gongo/files"
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+)
+
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+type Message struct {
+	From    string
+	To      []string
+	Cc      []string
+	Bcc     []string
+	ReplyTo string
+
+	Subject string
+	Text    string
+	HTML    string
+
+	Attachments []Attachment
+}
+
+// Attach adds attachment with data from r.
+func (m *Message) Attach(name, contentType string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return errors.Wrapf(err, "could not read attachment %s", name)
+	}
+
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	m.Attachments = append(m.Attachments, Attachment{
+		Name:        name,
+		ContentType: contentType,
+		Data:        data,
+	})
+
+	return nil
+}
+
+// AttachFile adds file stored with fs as attachment.
+func (m *Message) AttachFile(fs *files.Files, file files.FileItf) error {
+	r, err := fs.Open(file)
+	if err != nil {
+		return errors.Wrapf(err, "could not open file %s", file.GetID())
+	}
+	defer r.Close()
+
+	return m.Attach(file.GetName(), "", r)
+}
+
+// Recipients returns addresses of all recipients, including Bcc.
+func (m *Message) Recipients() ([]string, error) {
+	recipients := []string{}
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, address := range list {
+			parsed, err := parseAddress(address)
+			if err != nil {
+				return nil, err
+			}
+			recipients = append(recipients, parsed.Address)
+		}
+	}
+	return recipients, nil
+}
+
+// Sender returns address of the sender.
+func (m *Message) Sender() (string, error) {
+	parsed, err := parseAddress(m.From)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Address, nil
+}
+
+// addresses are parsed addresses of a message.
+type addresses struct {
+	from    *mail.Address
+	replyTo *mail.Address
+	to      []*mail.Address
+	cc      []*mail.Address
+}
+
+// Validate checks that message has a sender and recipients with valid
+// addresses and a subject without line breaks.
+func (m *Message) Validate() error {
+	_, err := m.parse()
+	return err
+}
+
+func (m *Message) parse() (*addresses, error) {
+	if m.From == "" {
+		return nil, errors.New("message has no sender")
+	}
+	if len(m.To)+len(m.Cc)+len(m.Bcc) == 0 {
+		return nil, errors.New("message has no recipients")
+	}
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return nil, errors.New("message subject contains a line break")
+	}
+
+	var err error
+	parsed := &addresses{}
+	if parsed.from, err = parseAddress(m.From); err != nil {
+		return nil, err
+	}
+	if m.ReplyTo != "" {
+		if parsed.replyTo, err = parseAddress(m.ReplyTo); err != nil {
+			return nil, err
+		}
+	}
+	if parsed.to, err = parseAddresses(m.To); err != nil {
+		return nil, err
+	}
+	if parsed.cc, err = parseAddresses(m.Cc); err != nil {
+		return nil, err
+	}
+	if _, err = parseAddresses(m.Bcc); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+func parseAddress(address string) (*mail.Address, error) {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse address %q", address)
+	}
+	return parsed, nil
+}
+
+func parseAddresses(list []string) ([]*mail.Address, error) {
+	parsed := make([]*mail.Address, len(list))
+	for i, address := range list {
+		var err error
+		if parsed[i], err = parseAddress(address); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// formatAddresses formats addresses for a header, names are quoted and
+// encoded, so they can not break the header.
+func formatAddresses(list []*mail.Address) string {
+	formatted := make([]string, len(list))
+	for i, address := range list {
+		formatted[i] = address.String()
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// Bytes encodes message as MIME email, without Bcc recipients. Headers are
+// formatted from parsed addresses, see Validate.
+func (m *Message) Bytes() ([]byte, error) {
+	parsed, err := m.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", parsed.from.String())
+	if len(parsed.to) > 0 {
+		header.Set("To", formatAddresses(parsed.to))
+	}
+	if len(parsed.cc) > 0 {
+		header.Set("Cc", formatAddresses(parsed.cc))
+	}
+	if parsed.replyTo != nil {
+		header.Set("Reply-To", parsed.replyTo.String())
+	}
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	messageID, err := newMessageID(parsed.from)
+	if err != nil {
+		return nil, err
+	}
+	header.Set("Message-Id", messageID)
+	header.Set("Mime-Version", "1.0")
+
+	email := &render.Email{
+		Text: m.Text,
+		HTML: m.HTML,
+	}
+
+	var body bytes.Buffer
+	bodyHeader, err := email.WriteBody(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Attachments) == 0 {
+		for key, values := range bodyHeader {
+			header[key] = values
+		}
+		writeHeader(&buf, header)
+		buf.Write(body.Bytes())
+		return buf.Bytes(), nil
+	}
+
+	var mixed bytes.Buffer
+	mw := multipart.NewWriter(&mixed)
+
+	pw, err := mw.CreatePart(bodyHeader)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create body part")
+	}
+	if _, err := pw.Write(body.Bytes()); err != nil {
+		return nil, errors.Wrap(err, "could not write body part")
+	}
+
+	for _, attachment := range m.Attachments {
+		mediaType, params, err := mime.ParseMediaType(attachment.ContentType)
+		if err != nil {
+			mediaType, params = "application/octet-stream", map[string]string{}
+		}
+		params["name"] = attachment.Name
+
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(mediaType, params)},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not create attachment %s", attachment.Name)
+		}
+		if err := writeBase64(pw, attachment.Data); err != nil {
+			return nil, errors.Wrapf(err, "could not write attachment %s", attachment.Name)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, errors.Wrap(err, "could not close message")
+	}
+
+	header.Set("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%q", mw.Boundary()))
+	writeHeader(&buf, header)
+	buf.Write(mixed.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	// fixed order makes messages easier to read
+	order := []string{"From", "To", "Cc", "Reply-To", "Subject", "Date", "Message-Id", "Mime-Version", "Content-Type", "Content-Transfer-Encoding"}
+	for _, key := range order {
+		for _, value := range header[key] {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+// writeBase64 writes data in lines of 76 characters.
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := 76
+		if len(encoded) < n {
+			n = len(encoded)
+		}
+		if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+func newMessageID(from *mail.Address) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "could not generate message id")
+	}
+
+	domain := "localhost"
+	if i := strings.LastIndex(from.Address, "@"); i >= 0 {
+		domain = from.Address[i+1:]
+	}
+
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">", nil
+}
@@ -0,0 +1,73 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBytesHeaders(t *testing.T) {
+	msg := &Message{
+		From:    "Shop <shop@example.com>",
+		To:      []string{"Žiga <ziga@example.com>", "ana@example.com"},
+		Bcc:     []string{"hidden@example.com"},
+		ReplyTo: "support@example.com",
+		Subject: "Naročilo",
+		Text:    "Hello",
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := string(data[:strings.Index(string(data), "\r\n\r\n")])
+
+	for _, want := range []string{
+		"From: \"Shop\" <shop@example.com>\r\n",
+		"To: =?utf-8?q?=C5=BDiga?= <ziga@example.com>, <ana@example.com>\r\n",
+		"Reply-To: <support@example.com>\r\n",
+		"Subject: =?utf-8?q?Naro=C4=8Dilo?=\r\n",
+		"Message-Id: <",
+	} {
+		if !strings.Contains(header+"\r\n", want) {
+			t.Errorf("header does not contain %q:\n%s", want, header)
+		}
+	}
+	if strings.Contains(string(data), "hidden@example.com") {
+		t.Errorf("message contains bcc recipient:\n%s", data)
+	}
+}
+
+func TestBytesRejectsInjection(t *testing.T) {
+	valid := func() *Message {
+		return &Message{
+			From:    "shop@example.com",
+			To:      []string{"ana@example.com"},
+			Subject: "Order",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(m *Message)
+	}{
+		{"from", func(m *Message) { m.From = "shop@example.com\r\nBcc: victim@example.com" }},
+		{"to", func(m *Message) { m.To = []string{"ana@example.com\r\nBcc: victim@example.com"} }},
+		{"cc", func(m *Message) { m.Cc = []string{"x\nBcc: victim@example.com"} }},
+		{"bcc", func(m *Message) { m.Bcc = []string{"not an address"} }},
+		{"reply to", func(m *Message) { m.ReplyTo = "a@example.com\r\nX-Injected: 1" }},
+		{"subject", func(m *Message) { m.Subject = "Order\r\nBcc: victim@example.com" }},
+		{"no sender", func(m *Message) { m.From = "" }},
+		{"no recipients", func(m *Message) { m.To = nil }},
+	}
+	for _, test := range tests {
+		msg := valid()
+		test.change(msg)
+		if _, err := msg.Bytes(); err == nil {
+			t.Errorf("%s: invalid message was encoded", test.name)
+		}
+	}
+
+	if _, err := valid().Bytes(); err != nil {
+		t.Errorf("valid message: %v", err)
+	}
+}
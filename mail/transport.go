@@ -0,0 +1,364 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Transport delivers messages.
+type Transport interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Inbox is implemented by transports that keep sent messages, they are
+// shown in the development inbox.
+type Inbox interface {
+	Sent() ([]Sent, error)
+}
+
+// Sent is a delivered message in MIME format.
+type Sent struct {
+	ID      string
+	Time    time.Time
+	To      string
+	Subject string
+	Raw     []byte
+}
+
+// smtpTimeout limits sending of a message when ctx has no deadline.
+const smtpTimeout = time.Minute
+
+type SMTP struct {
+	addr string
+	host string
+	auth smtp.Auth
+}
+
+// NewSMTP creates transport for SMTP server at addr (host:port), STARTTLS
+// is used when the server supports it. Username can be empty for servers
+// without authentication.
+func NewSMTP(addr, username, password string) (*SMTP, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse smtp address %s", addr)
+	}
+
+	t := &SMTP{
+		addr: addr,
+		host: host,
+	}
+	if username != "" {
+		t.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return t, nil
+}
+
+func (t *SMTP) Send(ctx context.Context, msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	from, err := msg.Sender()
+	if err != nil {
+		return err
+	}
+	recipients, err := msg.Recipients()
+	if err != nil {
+		return err
+	}
+
+	if err := t.send(ctx, from, recipients, data); err != nil {
+		return errors.Wrapf(err, "could not send mail to %s", strings.Join(recipients, ", "))
+	}
+
+	return nil
+}
+
+// send does what smtp.SendMail does, but on a connection that is closed
+// when ctx is done or its deadline passes.
+func (t *SMTP) send(ctx context.Context, from string, recipients []string, data []byte) error {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return errors.Wrapf(err, "could not connect to %s", t.addr)
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return errors.Wrap(err, "could not set deadline")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	c, err := smtp.NewClient(conn, t.host)
+	if err != nil {
+		return errors.Wrap(err, "could not start smtp session")
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: t.host}); err != nil {
+			return errors.Wrap(err, "could not start tls")
+		}
+	}
+	if t.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support authentication")
+		}
+		if err := c.Auth(t.auth); err != nil {
+			return errors.Wrap(err, "could not authenticate")
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		if err := c.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return errors.Wrap(err, "could not write mail")
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// File writes messages as .eml files to a folder instead of sending them,
+// for development.
+type File struct {
+	folder string
+}
+
+func NewFile(folder string) (*File, error) {
+	if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "could not create mail folder")
+	}
+
+	return &File{
+		folder: folder,
+	}, nil
+}
+
+func (t *File) Send(ctx context.Context, msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(t.folder, id+".eml")
+
+	if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		return errors.Wrapf(err, "could not write mail %s", name)
+	}
+
+	return nil
+}
+
+func (t *File) Sent() ([]Sent, error) {
+	names, err := filepath.Glob(filepath.Join(t.folder, "*.eml"))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list mails")
+	}
+
+	sent := make([]Sent, 0, len(names))
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read mail %s", name)
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not stat mail %s", name)
+		}
+
+		s := newSent(strings.TrimSuffix(filepath.Base(name), ".eml"), data)
+		s.Time = info.ModTime()
+		sent = append(sent, s)
+	}
+
+	sort.Slice(sent, func(i, j int) bool {
+		return sent[i].Time.After(sent[j].Time)
+	})
+
+	return sent, nil
+}
+
+// TB is the part of testing.TB used by Memory assertions.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Memory keeps messages in memory, for tests.
+type Memory struct {
+	mutex    sync.Mutex
+	messages []*Message
+	sent     []Sent
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (t *Memory) Send(ctx context.Context, msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	s := newSent(id, data)
+	s.Time = time.Now()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.messages = append(t.messages, msg)
+	t.sent = append([]Sent{s}, t.sent...)
+
+	return nil
+}
+
+func (t *Memory) Sent() ([]Sent, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	sent := make([]Sent, len(t.sent))
+	copy(sent, t.sent)
+	return sent, nil
+}
+
+// Messages returns sent messages in order they were sent.
+func (t *Memory) Messages() []*Message {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	messages := make([]*Message, len(t.messages))
+	copy(messages, t.messages)
+	return messages
+}
+
+func (t *Memory) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.messages = nil
+	t.sent = nil
+}
+
+// Find returns messages sent to address with subject containing subject.
+func (t *Memory) Find(address, subject string) []*Message {
+	found := []*Message{}
+	for _, msg := range t.Messages() {
+		if !strings.Contains(msg.Subject, subject) {
+			continue
+		}
+		recipients, err := msg.Recipients()
+		if err != nil {
+			continue
+		}
+		for _, recipient := range recipients {
+			if strings.EqualFold(recipient, address) {
+				found = append(found, msg)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// AssertSent fails the test if no message was sent to address with
+// subject containing subject, and returns the last such message.
+func (t *Memory) AssertSent(tb TB, address, subject string) *Message {
+	tb.Helper()
+
+	found := t.Find(address, subject)
+	if len(found) == 0 {
+		tb.Errorf("no mail with subject %q sent to %s", subject, address)
+		return nil
+	}
+	return found[len(found)-1]
+}
+
+// AssertCount fails the test if not exactly n messages were sent.
+func (t *Memory) AssertCount(tb TB, n int) {
+	tb.Helper()
+
+	if count := len(t.Messages()); count != n {
+		tb.Errorf("expected %d mails to be sent, got %d", n, count)
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "could not generate id")
+	}
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b), nil
+}
+
+func newSent(id string, data []byte) Sent {
+	s := Sent{
+		ID:  id,
+		Raw: data,
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return s
+	}
+
+	decoder := mime.WordDecoder{}
+	s.To = parsed.Header.Get("To")
+	s.Subject = parsed.Header.Get("Subject")
+	if subject, err := decoder.DecodeHeader(s.Subject); err == nil {
+		s.Subject = subject
+	}
+
+	return s
+}
@@ -0,0 +1,36 @@
+package gongo
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Mounter is implemented by components that serve their own routes under a
+// path prefix.
+type Mounter interface {
+	Prefix() string
+	ServeMux() http.Handler
+}
+
+// Middlewarer is implemented by components that wrap every request.
+type Middlewarer interface {
+	Middleware(next http.Handler) http.Handler
+}
+
+// PathPrefix returns path part of urlPrefix, which can be either a full url
+// or just a path.
+func PathPrefix(urlPrefix string) string {
+	u, err := url.Parse(urlPrefix)
+	if err != nil {
+		return urlPrefix
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// HasPathPrefix reports if path is prefix or is under it, so /admin matches
+// /admin/users but not /administrator.
+func HasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,43 @@
+package gongo
+
+import "testing"
+
+func TestHasPathPrefix(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		want   bool
+	}{
+		{"/admin", "/admin", true},
+		{"/admin/users", "/admin", true},
+		{"/admin/users", "/admin/", true},
+		{"/administrator", "/admin", false},
+		{"/administrator", "/admin/", false},
+		{"/", "/admin", false},
+		{"/anything", "", true},
+		{"/anything", "/", true},
+	}
+	for _, test := range tests {
+		if got := HasPathPrefix(test.path, test.prefix); got != test.want {
+			t.Errorf("HasPathPrefix(%q, %q): got %v, want %v", test.path, test.prefix, got, test.want)
+		}
+	}
+}
+
+func TestPathPrefix(t *testing.T) {
+	tests := []struct {
+		urlPrefix string
+		want      string
+	}{
+		{"/admin", "/admin"},
+		{"/admin/", "/admin"},
+		{"https://example.com/auth/", "/auth"},
+		{"https://example.com", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := PathPrefix(test.urlPrefix); got != test.want {
+			t.Errorf("PathPrefix(%q): got %q, want %q", test.urlPrefix, got, test.want)
+		}
+	}
+}
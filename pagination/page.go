@@ -0,0 +1,399 @@
+package pagination
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/matematik7/gongo/i18n"
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultSize    = 20
+	DefaultMaxSize = 100
+)
+
+// Options configure which query parameters Paginate accepts.
+type Options struct {
+	DefaultSize int
+	MaxSize     int
+
+	// Sorts maps values of the sort parameter to columns, -name sorts
+	// descending.
+	Sorts map[string]string
+	// DefaultSort is used without sort parameter, for example "-created".
+	DefaultSort string
+
+	// Filters maps query parameters to columns, that have to be equal to
+	// the value, or one of values if the parameter is repeated.
+	Filters map[string]string
+
+	// Cursor enables keyset pagination with after and before parameters,
+	// that is fast for large tables, but has no page numbers.
+	Cursor bool
+	// Key is unique column that orders rows with equal sort column in
+	// cursor mode, id by default.
+	Key string
+}
+
+// Page is metadata of the paginated list.
+type Page struct {
+	Number int    `json:"page,omitempty" xml:"page,omitempty"`
+	Size   int    `json:"size" xml:"size"`
+	Total  int    `json:"total,omitempty" xml:"total,omitempty"`
+	Pages  int    `json:"pages,omitempty" xml:"pages,omitempty"`
+	Sort   string `json:"sort,omitempty" xml:"sort,omitempty"`
+
+	HasNext bool   `json:"has_next" xml:"has_next"`
+	HasPrev bool   `json:"has_prev" xml:"has_prev"`
+	Next    string `json:"next,omitempty" xml:"next,omitempty"`
+	Prev    string `json:"prev,omitempty" xml:"prev,omitempty"`
+
+	url *url.URL
+}
+
+// Link is a link to a page, Gap marks skipped pages.
+type Link struct {
+	Number  int
+	URL     string
+	Current bool
+	Gap     bool
+}
+
+// Paginate applies filters, sort and page from query parameters of r to
+// query and finds the page into items, which must be a pointer to a slice.
+// Invalid parameters are reported as BadRequestError.
+func Paginate(query *gorm.DB, r *http.Request, opts Options, items interface{}) (*Page, error) {
+	if opts.DefaultSize <= 0 {
+		opts.DefaultSize = DefaultSize
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.Key == "" {
+		opts.Key = "id"
+	}
+
+	values := r.URL.Query()
+	page := &Page{
+		Size: opts.DefaultSize,
+		Sort: opts.DefaultSort,
+		url:  r.URL,
+	}
+
+	if size := values.Get("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n <= 0 {
+			return nil, render.BadRequestError{Message: i18n.T(r.Context(), "Invalid page size %s.", size)}
+		}
+		if n > opts.MaxSize {
+			n = opts.MaxSize
+		}
+		page.Size = n
+	}
+
+	for param, column := range opts.Filters {
+		if filter, ok := values[param]; ok {
+			if len(filter) == 1 {
+				query = query.Where(quote(query, column)+" = ?", filter[0])
+			} else {
+				query = query.Where(quote(query, column)+" IN (?)", filter)
+			}
+		}
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		if _, ok := opts.Sorts[strings.TrimPrefix(sort, "-")]; !ok {
+			return nil, render.BadRequestError{Message: i18n.T(r.Context(), "Invalid sort %s.", sort)}
+		}
+		page.Sort = sort
+	}
+	column, desc := "", false
+	if page.Sort != "" {
+		var ok bool
+		column, ok = opts.Sorts[strings.TrimPrefix(page.Sort, "-")]
+		if !ok {
+			return nil, errors.Errorf("default sort %s is not in sorts", page.Sort)
+		}
+		desc = strings.HasPrefix(page.Sort, "-")
+	}
+
+	if opts.Cursor {
+		if err := page.findCursor(r.Context(), query, values, opts, column, desc, items); err != nil {
+			return nil, err
+		}
+		return page, nil
+	}
+
+	page.Number = 1
+	if number := values.Get("page"); number != "" {
+		n, err := strconv.Atoi(number)
+		if err != nil || n <= 0 {
+			return nil, render.BadRequestError{Message: i18n.T(r.Context(), "Invalid page %s.", number)}
+		}
+		page.Number = n
+	}
+
+	if err := query.Model(items).Count(&page.Total).Error; err != nil {
+		return nil, errors.Wrap(err, "could not count items")
+	}
+	page.Pages = (page.Total + page.Size - 1) / page.Size
+
+	if column != "" {
+		query = query.Order(order(query, column, desc))
+	}
+	query = query.Order(order(query, opts.Key, desc))
+	err := query.Offset((page.Number - 1) * page.Size).Limit(page.Size).Find(items).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "could not find items")
+	}
+
+	page.HasPrev = page.Number > 1
+	page.HasNext = page.Number < page.Pages
+	if page.HasPrev {
+		page.Prev = page.URL(page.Number - 1)
+	}
+	if page.HasNext {
+		page.Next = page.URL(page.Number + 1)
+	}
+
+	return page, nil
+}
+
+// findCursor finds items after or before the cursor, ordered by column and
+// key.
+func (page *Page) findCursor(ctx context.Context, query *gorm.DB, values url.Values, opts Options, column string, desc bool, items interface{}) error {
+	after, before := values.Get("after"), values.Get("before")
+	if after != "" && before != "" {
+		return render.BadRequestError{Message: i18n.T(ctx, "Only one of after and before can be used.")}
+	}
+
+	sliceValue := reflect.ValueOf(items)
+	if sliceValue.Kind() != reflect.Ptr || sliceValue.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("items must be a pointer to slice, got %T", items)
+	}
+	elem := reflect.New(sliceValue.Elem().Type().Elem()).Interface()
+
+	columns := []string{opts.Key}
+	if column != "" {
+		columns = []string{column, opts.Key}
+	}
+
+	// before pages are loaded in reverse order and reversed afterwards
+	reverse := before != ""
+	cursor := after
+	if reverse {
+		cursor = before
+	}
+
+	if cursor != "" {
+		cursorValues, err := decodeCursor(query, elem, columns, cursor)
+		if err != nil {
+			return render.BadRequestError{Message: i18n.T(ctx, "Invalid cursor.")}
+		}
+		query = query.Where(keyset(query, columns, desc != reverse), cursorValues...)
+	}
+
+	for _, c := range columns {
+		query = query.Order(order(query, c, desc != reverse))
+	}
+	if err := query.Limit(page.Size + 1).Find(items).Error; err != nil {
+		return errors.Wrap(err, "could not find items")
+	}
+
+	slice := sliceValue.Elem()
+	more := slice.Len() > page.Size
+	if more {
+		slice.Set(slice.Slice(0, page.Size))
+	}
+	if reverse {
+		for i, j := 0, slice.Len()-1; i < j; i, j = i+1, j-1 {
+			a, b := slice.Index(i).Interface(), slice.Index(j).Interface()
+			slice.Index(i).Set(reflect.ValueOf(b))
+			slice.Index(j).Set(reflect.ValueOf(a))
+		}
+	}
+
+	if reverse {
+		page.HasPrev = more
+		page.HasNext = true
+	} else {
+		page.HasPrev = after != ""
+		page.HasNext = more
+	}
+
+	if slice.Len() == 0 {
+		return nil
+	}
+
+	if page.HasNext {
+		next, err := encodeCursor(query, slice.Index(slice.Len()-1), columns)
+		if err != nil {
+			return err
+		}
+		page.Next = page.with("after", next, "before")
+	}
+	if page.HasPrev {
+		prev, err := encodeCursor(query, slice.Index(0), columns)
+		if err != nil {
+			return err
+		}
+		page.Prev = page.with("before", prev, "after")
+	}
+
+	return nil
+}
+
+// keyset returns condition for rows after the cursor, for example
+// (a > ?) OR (a = ? AND id > ?), with values repeated by decodeCursor.
+func keyset(query *gorm.DB, columns []string, desc bool) string {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+
+	conditions := make([]string, len(columns))
+	for i := range columns {
+		parts := make([]string, 0, i+1)
+		for _, c := range columns[:i] {
+			parts = append(parts, quote(query, c)+" = ?")
+		}
+		parts = append(parts, quote(query, columns[i])+" "+op+" ?")
+		conditions[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+
+	return strings.Join(conditions, " OR ")
+}
+
+func encodeCursor(query *gorm.DB, item reflect.Value, columns []string) (string, error) {
+	scope := query.NewScope(item.Addr().Interface())
+
+	values := make([]interface{}, len(columns))
+	for i, c := range columns {
+		field, ok := scope.FieldByName(fieldName(c))
+		if !ok {
+			return "", errors.Errorf("could not find cursor column %s", c)
+		}
+		values[i] = field.Field.Interface()
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", errors.Wrap(err, "could not encode cursor")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns values for the keyset condition, decoded to types
+// of the columns.
+func decodeCursor(query *gorm.DB, elem interface{}, columns []string, cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode cursor")
+	}
+
+	raw := []json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrap(err, "could not decode cursor")
+	}
+	if len(raw) != len(columns) {
+		return nil, errors.New("cursor does not match sort")
+	}
+
+	scope := query.NewScope(elem)
+	values := make([]interface{}, len(columns))
+	for i, c := range columns {
+		field, ok := scope.FieldByName(fieldName(c))
+		if !ok {
+			return nil, errors.Errorf("could not find cursor column %s", c)
+		}
+		value := reflect.New(field.Field.Type())
+		if err := json.Unmarshal(raw[i], value.Interface()); err != nil {
+			return nil, errors.Wrap(err, "could not decode cursor value")
+		}
+		values[i] = value.Elem().Interface()
+	}
+
+	// each condition repeats equality values of previous columns
+	args := []interface{}{}
+	for i := range columns {
+		args = append(args, values[:i+1]...)
+	}
+
+	return args, nil
+}
+
+func order(query *gorm.DB, column string, desc bool) string {
+	if desc {
+		return quote(query, column) + " DESC"
+	}
+	return quote(query, column) + " ASC"
+}
+
+// quote quotes each part of column, so table.column becomes
+// "table"."column".
+func quote(query *gorm.DB, column string) string {
+	parts := strings.Split(column, ".")
+	for i, part := range parts {
+		parts[i] = query.Dialect().Quote(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// fieldName returns column without the table, to find its field.
+func fieldName(column string) string {
+	return column[strings.LastIndex(column, ".")+1:]
+}
+
+// URL returns url of page number, preserving other query parameters.
+func (page *Page) URL(number int) string {
+	return page.with("page", strconv.Itoa(number))
+}
+
+// with returns current url with param set to value and without params in
+// remove.
+func (page *Page) with(param, value string, remove ...string) string {
+	values := page.url.Query()
+	values.Set(param, value)
+	for _, r := range remove {
+		values.Del(r)
+	}
+
+	u := *page.url
+	u.RawQuery = values.Encode()
+	return u.RequestURI()
+}
+
+// Links returns links to first and last page and window pages around the
+// current page, with gaps between them. Cursor pages have no links.
+func (page *Page) Links(window int) []Link {
+	if page.Pages <= 1 {
+		return nil
+	}
+
+	links := []Link{}
+	for n := 1; n <= page.Pages; n++ {
+		if n != 1 && n != page.Pages && (n < page.Number-window || n > page.Number+window) {
+			if !links[len(links)-1].Gap {
+				links = append(links, Link{Gap: true})
+			}
+			continue
+		}
+		links = append(links, Link{
+			Number:  n,
+			URL:     page.URL(n),
+			Current: n == page.Number,
+		})
+	}
+
+	return links
+}
@@ -0,0 +1,161 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+type item struct {
+	ID    uint
+	Score int
+}
+
+func newDB(t *testing.T, scores ...int) *gorm.DB {
+	DB, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DB.Close() })
+
+	if err := DB.AutoMigrate(&item{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, score := range scores {
+		if err := DB.Create(&item{Score: score}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return DB
+}
+
+func ids(items []item) []uint {
+	result := make([]uint, len(items))
+	for i, it := range items {
+		result[i] = it.ID
+	}
+	return result
+}
+
+func TestQuote(t *testing.T) {
+	DB := newDB(t)
+
+	if got := quote(DB, "score"); got != `"score"` {
+		t.Errorf("got %s", got)
+	}
+	if got := quote(DB, "items.score"); got != `"items"."score"` {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	DB := newDB(t)
+	columns := []string{"items.score", "id"}
+
+	cursor, err := encodeCursor(DB, reflect.ValueOf(&item{ID: 7, Score: 3}).Elem(), columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := decodeCursor(DB, &item{}, columns, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// equality values of previous columns are repeated for every condition
+	want := []interface{}{3, 3, uint(7)}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("got %v, want %v", args, want)
+	}
+
+	for _, invalid := range []string{"not base64!", "bm90IGpzb24", "WzFd"} {
+		if _, err := decodeCursor(DB, &item{}, columns, invalid); err == nil {
+			t.Errorf("cursor %s was accepted", invalid)
+		}
+	}
+}
+
+// TestCursorTieBreak pages through items with equal sort values, which are
+// ordered by the key, so no item is skipped or repeated.
+func TestCursorTieBreak(t *testing.T) {
+	opts := Options{
+		Sorts:  map[string]string{"score": "items.score"},
+		Cursor: true,
+	}
+
+	tests := []struct {
+		sort string
+		want []uint
+	}{
+		{sort: "score", want: []uint{2, 4, 5, 1, 3}},
+		{sort: "-score", want: []uint{3, 1, 5, 4, 2}},
+	}
+	for _, test := range tests {
+		DB := newDB(t, 2, 1, 2, 1, 1)
+
+		got := []uint{}
+		prev := ""
+		target := "/?size=2&sort=" + test.sort
+		for pages := 0; target != ""; pages++ {
+			if pages > len(test.want) {
+				t.Fatalf("%s: too many pages", test.sort)
+			}
+
+			items := []item{}
+			page, err := Paginate(DB, httptest.NewRequest("GET", target, nil), opts, &items)
+			if err != nil {
+				t.Fatalf("%s: %v", test.sort, err)
+			}
+			got = append(got, ids(items)...)
+			target, prev = page.Next, page.Prev
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.sort, got, test.want)
+		}
+
+		// going back from the last page returns the same items
+		got = []uint{}
+		for pages := 0; prev != ""; pages++ {
+			if pages > len(test.want) {
+				t.Fatalf("%s: too many pages", test.sort)
+			}
+
+			items := []item{}
+			page, err := Paginate(DB, httptest.NewRequest("GET", prev, nil), opts, &items)
+			if err != nil {
+				t.Fatalf("%s: %v", test.sort, err)
+			}
+			got = append(ids(items), got...)
+			prev = page.Prev
+		}
+		last := test.want[len(test.want)-1]
+		if want := test.want[:len(test.want)-1]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v before %d, want %v", test.sort, got, last, want)
+		}
+	}
+}
+
+func TestPaginateOffset(t *testing.T) {
+	DB := newDB(t, 1, 2, 3, 4, 5)
+
+	items := []item{}
+	page, err := Paginate(DB, httptest.NewRequest("GET", "/?size=2&page=2&sort=-score", nil), Options{
+		Sorts: map[string]string{"score": "score"},
+	}, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ids(items); !reflect.DeepEqual(got, []uint{3, 2}) {
+		t.Errorf("got %v", got)
+	}
+	if page.Total != 5 || page.Pages != 3 || !page.HasPrev || !page.HasNext {
+		t.Errorf("got %+v", page)
+	}
+	if page.Next != "/?page=3&size=2&sort=-score" {
+		t.Errorf("got next %s", page.Next)
+	}
+}
@@ -0,0 +1,47 @@
+package pagination
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+)
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// Pagination provides pagination/links.html template and page_links
+// template function.
+type Pagination struct{}
+
+func New() *Pagination {
+	return &Pagination{}
+}
+
+func (p *Pagination) Dependencies() []string {
+	return []string{"Render"}
+}
+
+func (p *Pagination) Configure(app *gongo.App) error {
+	var r *render.Render
+	if err := app.Lookup("Render", &r); err != nil {
+		return err
+	}
+
+	templates, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return errors.Wrap(err, "could not load default templates")
+	}
+	r.AddDefaultTemplates(http.FS(templates))
+
+	r.AddContextFunc(func(req *http.Request, ctx render.Context) {
+		ctx["page_links"] = func(page *Page, window int) []Link {
+			return page.Links(window)
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package gongo
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// InstanceID identifies this process among app instances that share a
+// database, for example in locks of background workers.
+func InstanceID() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// SafeCall calls fn and returns its panic as an error, so panics in
+// background work do not crash the app.
+func SafeCall(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("panic: %v", r)
+		}
+	}()
+	return fn()
+}
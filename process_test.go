@@ -0,0 +1,22 @@
+package gongo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSafeCall(t *testing.T) {
+	if err := SafeCall(func() error { return nil }); err != nil {
+		t.Errorf("got %v", err)
+	}
+
+	failed := errors.New("failed")
+	if err := SafeCall(func() error { return failed }); err != failed {
+		t.Errorf("got %v, want %v", err, failed)
+	}
+
+	err := SafeCall(func() error { panic("boom") })
+	if err == nil || err.Error() != "panic: boom" {
+		t.Errorf("got %v", err)
+	}
+}
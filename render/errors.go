@@ -0,0 +1,260 @@
+package render
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matematik7/gongo"
+	"github.com/pkg/errors"
+)
+
+var errorMessages = map[int]string{
+	http.StatusBadRequest:          "The request could not be understood.",
+	http.StatusUnauthorized:        "You need to log in to see this page.",
+	http.StatusForbidden:           "The Force is not strong with you.",
+	http.StatusNotFound:            "This is not the web page you are looking for.",
+	http.StatusMethodNotAllowed:    "Your position's correct, except... not this method.",
+	http.StatusConflict:            "Somebody else changed this in the meantime.",
+	http.StatusUnprocessableEntity: "Please correct the errors below.",
+	http.StatusTooManyRequests:     "Too many requests, please slow down.",
+	http.StatusInternalServerError: "Sorry, something went wrong.",
+	http.StatusServiceUnavailable:  "We are down for maintenance, please come back later.",
+}
+
+// StatusError is implemented by errors that are rendered with a specific
+// HTTP status by HandleError.
+type StatusError interface {
+	error
+	Status() int
+}
+
+// publicError is implemented by errors with a message that is safe to
+// show to users.
+type publicError interface {
+	publicMessage() string
+}
+
+type NotFoundError struct {
+	Message string
+}
+
+func (e NotFoundError) Error() string         { return messageOr(e.Message, "not found") }
+func (e NotFoundError) Status() int           { return http.StatusNotFound }
+func (e NotFoundError) publicMessage() string { return e.Message }
+
+// BadRequestError reports invalid input, optionally per form field.
+type BadRequestError struct {
+	Message string
+	Fields  map[string]string
+}
+
+func (e BadRequestError) Error() string         { return messageOr(e.Message, "bad request") }
+func (e BadRequestError) Status() int           { return http.StatusBadRequest }
+func (e BadRequestError) publicMessage() string { return e.Message }
+
+// ValidationError reports well-formed input that failed validation, per
+// form field.
+type ValidationError struct {
+	Message string
+	Fields  map[string]string
+}
+
+func (e ValidationError) Error() string         { return messageOr(e.Message, "validation failed") }
+func (e ValidationError) Status() int           { return http.StatusUnprocessableEntity }
+func (e ValidationError) publicMessage() string { return e.Message }
+
+// ConflictError reports a change that conflicts with the current state,
+// for example an edit of a stale version.
+type ConflictError struct {
+	Message string
+}
+
+func (e ConflictError) Error() string         { return messageOr(e.Message, "conflict") }
+func (e ConflictError) Status() int           { return http.StatusConflict }
+func (e ConflictError) publicMessage() string { return e.Message }
+
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e UnauthorizedError) Error() string         { return messageOr(e.Message, "unauthorized") }
+func (e UnauthorizedError) Status() int           { return http.StatusUnauthorized }
+func (e UnauthorizedError) publicMessage() string { return e.Message }
+
+type ForbiddenError struct {
+	Message string
+}
+
+func (e ForbiddenError) Error() string         { return messageOr(e.Message, "forbidden") }
+func (e ForbiddenError) Status() int           { return http.StatusForbidden }
+func (e ForbiddenError) publicMessage() string { return e.Message }
+
+// RateLimitedError asks the client to retry after Retry, if set.
+type RateLimitedError struct {
+	Message string
+	Retry   time.Duration
+}
+
+func (e RateLimitedError) Error() string         { return messageOr(e.Message, "rate limited") }
+func (e RateLimitedError) Status() int           { return http.StatusTooManyRequests }
+func (e RateLimitedError) publicMessage() string { return e.Message }
+
+// MaintenanceError asks the client to retry after Retry, if set.
+type MaintenanceError struct {
+	Message string
+	Retry   time.Duration
+}
+
+func (e MaintenanceError) Error() string         { return messageOr(e.Message, "maintenance") }
+func (e MaintenanceError) Status() int           { return http.StatusServiceUnavailable }
+func (e MaintenanceError) publicMessage() string { return e.Message }
+
+func messageOr(msg, def string) string {
+	if msg == "" {
+		return def
+	}
+	return msg
+}
+
+type errorPage struct {
+	status int
+	msg    string
+	fields map[string]string
+}
+
+// HandleError renders err with the status of the StatusError it wraps, or
+// as an internal server error.
+func (r *Render) HandleError(w http.ResponseWriter, req *http.Request, err error) {
+	// client went away while writing the response, nothing to render
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "write" {
+		return
+	}
+
+	var statusErr StatusError
+	if errors.As(err, &statusErr) {
+		r.HTTPError(w, req, statusErr.Status(), err)
+		return
+	}
+
+	r.Error(w, req, err)
+}
+
+// HTTPError renders error page for status. Messages of typed errors are
+// shown to users, other errors are only logged.
+func (r *Render) HTTPError(w http.ResponseWriter, req *http.Request, status int, err error) {
+	if status == http.StatusInternalServerError {
+		if err == nil {
+			err = errors.New(http.StatusText(status))
+		}
+		r.Error(w, req, err)
+		return
+	}
+
+	page := errorPage{
+		status: status,
+		msg:    r.translate(req, errorMessages[status]),
+	}
+
+	if err != nil {
+		if status >= http.StatusInternalServerError && status != http.StatusServiceUnavailable {
+			r.logging.WithRequest(req).WithField("Status", status).Error(err.Error())
+		}
+
+		var public publicError
+		if errors.As(err, &public) && public.publicMessage() != "" {
+			page.msg = public.publicMessage()
+		}
+
+		var badRequest BadRequestError
+		if errors.As(err, &badRequest) {
+			page.fields = badRequest.Fields
+		}
+		var validation ValidationError
+		if errors.As(err, &validation) {
+			page.fields = validation.Fields
+		}
+
+		var rateLimited RateLimitedError
+		if errors.As(err, &rateLimited) {
+			setRetryAfter(w, rateLimited.Retry)
+		}
+		var maintenance MaintenanceError
+		if errors.As(err, &maintenance) {
+			setRetryAfter(w, maintenance.Retry)
+		}
+	}
+
+	if err := r.renderError(w, req, page); err != nil {
+		r.Error(w, req, err)
+	}
+}
+
+func setRetryAfter(w http.ResponseWriter, retry time.Duration) {
+	if retry > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((retry+time.Second-1)/time.Second)))
+	}
+}
+
+// AddErrorTemplates makes error pages of requests with path under urlPrefix
+// use templates from dir (dir/errors/404.html, dir/error.html) before the
+// global ones.
+func (r *Render) AddErrorTemplates(urlPrefix, dir string) {
+	r.errorTemplates = append(r.errorTemplates, errorTemplates{
+		urlPrefix: urlPrefix,
+		dir:       dir,
+	})
+	// longest prefix is the most specific
+	sort.SliceStable(r.errorTemplates, func(i, j int) bool {
+		return len(r.errorTemplates[i].urlPrefix) > len(r.errorTemplates[j].urlPrefix)
+	})
+}
+
+type errorTemplates struct {
+	urlPrefix string
+	dir       string
+}
+
+func (r *Render) errorTemplate(req *http.Request, status int) string {
+	dirs := []string{}
+	for _, et := range r.errorTemplates {
+		if gongo.HasPathPrefix(req.URL.Path, et.urlPrefix) {
+			dirs = append(dirs, strings.Trim(et.dir, "/")+"/")
+		}
+	}
+	dirs = append(dirs, "")
+
+	for _, dir := range dirs {
+		for _, name := range []string{"errors/" + strconv.Itoa(status) + ".html", "error.html"} {
+			if r.loader.Exists(dir + name) {
+				return dir + name
+			}
+		}
+	}
+
+	return "error.html"
+}
+
+// renderError renders error template for browsers and a problem document
+// for API clients.
+func (r *Render) renderError(w http.ResponseWriter, req *http.Request, page errorPage) error {
+	title := r.translate(req, http.StatusText(page.status))
+
+	switch Negotiate(req, mimeHTML, mimeJSON, mimeXML) {
+	case mimeJSON:
+		return writeJSON(w, page.status, mimeProblemJSON, newProblem(req, page.status, title, page.msg, page.fields))
+	case mimeXML:
+		return writeXML(w, page.status, mimeProblemXML, newProblem(req, page.status, title, page.msg, page.fields))
+	}
+
+	return r.renderTemplate(w, req, page.status, r.errorTemplate(req, page.status), Context{
+		"status": page.status,
+		"title":  title,
+		"msg":    page.msg,
+		"fields": page.fields,
+	})
+}
@@ -0,0 +1,54 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// ETag returns weak entity tag of data. It is weak, because the response
+// can still be compressed on the way.
+func ETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NoneMatch reports whether etag does not match any tag from If-None-Match
+// header of req, so the full response has to be sent.
+func NoneMatch(req *http.Request, etag string) bool {
+	header := req.Header.Get("If-None-Match")
+	if header == "" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeETag writes data with its ETag, or only 304 status if the client
+// already has it.
+func writeETag(w http.ResponseWriter, req *http.Request, data []byte) {
+	etag := ETag(data)
+	w.Header().Set("ETag", etag)
+
+	if !NoneMatch(req, etag) {
+		header := w.Header()
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// Do not handle errors, the client is probably gone
+	w.WriteHeader(http.StatusOK)
+	if req.Method != http.MethodHead {
+		w.Write(data)
+	}
+}
@@ -0,0 +1,138 @@
+package render
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+type FlashLevel string
+
+const (
+	FlashSuccess FlashLevel = "success"
+	FlashInfo    FlashLevel = "info"
+	FlashWarning FlashLevel = "warning"
+	FlashError   FlashLevel = "error"
+)
+
+type Flash struct {
+	Level    FlashLevel `json:"level"`
+	Title    string     `json:"title,omitempty"`
+	Message  string     `json:"message"`
+	Link     string     `json:"link,omitempty"`
+	LinkText string     `json:"link_text,omitempty"`
+}
+
+func init() {
+	gob.Register(Flash{})
+}
+
+// AddFlash stores flash in the session until it is rendered. Strings are
+// stored as info flashes, other values must be registered with gob.
+func (r *Render) AddFlash(w http.ResponseWriter, req *http.Request, flash interface{}) error {
+	switch f := flash.(type) {
+	case nil:
+		return errors.New("flash is nil")
+	case Flash:
+	case *Flash:
+		if f == nil {
+			return errors.New("flash is nil")
+		}
+		flash = *f
+	case string:
+		flash = Flash{Level: FlashInfo, Message: f}
+	default:
+		// session stores do not report values they can not encode
+		if err := gob.NewEncoder(ioutil.Discard).Encode(&struct{ V interface{} }{flash}); err != nil {
+			return errors.Wrapf(err, "flash of type %T can not be stored", flash)
+		}
+	}
+
+	session, err := r.store.Get(req, "render")
+	if err != nil {
+		return errors.Wrap(err, "could not get store")
+	}
+
+	session.AddFlash(flash)
+
+	if err := session.Save(req, w); err != nil {
+		return errors.Wrap(err, "could not save session")
+	}
+
+	return nil
+}
+
+func (r *Render) addFlash(w http.ResponseWriter, req *http.Request, level FlashLevel, msg string, args []interface{}) error {
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	return r.AddFlash(w, req, Flash{Level: level, Message: msg})
+}
+
+func (r *Render) AddSuccess(w http.ResponseWriter, req *http.Request, msg string, args ...interface{}) error {
+	return r.addFlash(w, req, FlashSuccess, msg, args)
+}
+
+func (r *Render) AddInfo(w http.ResponseWriter, req *http.Request, msg string, args ...interface{}) error {
+	return r.addFlash(w, req, FlashInfo, msg, args)
+}
+
+func (r *Render) AddWarning(w http.ResponseWriter, req *http.Request, msg string, args ...interface{}) error {
+	return r.addFlash(w, req, FlashWarning, msg, args)
+}
+
+func (r *Render) AddError(w http.ResponseWriter, req *http.Request, msg string, args ...interface{}) error {
+	return r.addFlash(w, req, FlashError, msg, args)
+}
+
+// Flashes returns and removes all flashes from the session, for example to
+// include them in an API response.
+func (r *Render) Flashes(w http.ResponseWriter, req *http.Request) ([]Flash, error) {
+	session, err := r.store.Get(req, "render")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get render store")
+	}
+
+	values := session.Flashes()
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	if err := session.Save(req, w); err != nil {
+		return nil, errors.Wrap(err, "could not save render session")
+	}
+
+	return toFlashes(values), nil
+}
+
+// PeekFlashes returns flashes without removing them, so they are still
+// rendered after a redirect.
+func (r *Render) PeekFlashes(req *http.Request) ([]Flash, error) {
+	session, err := r.store.Get(req, "render")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get render store")
+	}
+
+	// gorilla sessions keep flashes under this key by default
+	values, _ := session.Values["_flash"].([]interface{})
+
+	return toFlashes(values), nil
+}
+
+func toFlashes(values []interface{}) []Flash {
+	flashes := make([]Flash, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case Flash:
+			flashes[i] = v
+		case string:
+			flashes[i] = Flash{Level: FlashInfo, Message: v}
+		default:
+			flashes[i] = Flash{Level: FlashInfo, Message: fmt.Sprint(v)}
+		}
+	}
+	return flashes
+}
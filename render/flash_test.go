@@ -0,0 +1,98 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// withCookies returns request with cookies set by w, the session is saved
+// once per flash, so only the last cookie of a name is sent.
+func withCookies(w *httptest.ResponseRecorder) *http.Request {
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+func TestFlashes(t *testing.T) {
+	r := newRender(t, fstest.MapFS{})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", nil)
+	if err := r.AddSuccess(w, req, "Saved %d notes.", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddFlash(w, req, &Flash{Level: FlashWarning, Title: "Careful", Message: "Almost full."}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddFlash(w, req, "Plain message."); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Flash{
+		{Level: FlashSuccess, Message: "Saved 2 notes."},
+		{Level: FlashWarning, Title: "Careful", Message: "Almost full."},
+		{Level: FlashInfo, Message: "Plain message."},
+	}
+
+	peeked, err := r.PeekFlashes(withCookies(w))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(peeked, want) {
+		t.Errorf("PeekFlashes: got %v, want %v", peeked, want)
+	}
+
+	next := httptest.NewRecorder()
+	flashes, err := r.Flashes(next, withCookies(w))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(flashes, want) {
+		t.Errorf("Flashes: got %v, want %v", flashes, want)
+	}
+
+	// flashes are removed once read
+	flashes, err = r.Flashes(httptest.NewRecorder(), withCookies(next))
+	if err != nil || len(flashes) != 0 {
+		t.Errorf("Flashes after read: got %v, %v", flashes, err)
+	}
+}
+
+type unregistered struct {
+	Message string
+}
+
+func TestAddFlashErrors(t *testing.T) {
+	r := newRender(t, fstest.MapFS{})
+
+	tests := []struct {
+		name  string
+		flash interface{}
+		want  string
+	}{
+		{"nil", nil, "flash is nil"},
+		{"nil pointer", (*Flash)(nil), "flash is nil"},
+		{"unregistered", unregistered{"hi"}, "flash of type render.unregistered can not be stored"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		err := r.AddFlash(w, httptest.NewRequest("POST", "/", nil), test.flash)
+		if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("%s: got %v, want %s", test.name, err, test.want)
+		}
+		if cookies := w.Result().Cookies(); len(cookies) != 0 {
+			t.Errorf("%s: session was saved", test.name)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package render
+
+import (
+	"net/http"
+
+	"github.com/matematik7/gongo"
+)
+
+// Handler adapts h to http.HandlerFunc, rendering returned errors with
+// HandleError.
+func (r *Render) Handler(h gongo.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if err := h(w, req); err != nil {
+			r.HandleError(w, req, err)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package render
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+type cssRule struct {
+	selector    []cssSelector
+	specificity int
+	order       int
+	decls       []cssDecl
+}
+
+// cssSelector is a compound selector, like p.note#intro.
+type cssSelector struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+type cssDecl struct {
+	property string
+	value    string
+}
+
+// InlineCSS moves rules from style elements of document to style
+// attributes of matching elements. Only type, class and id selectors with
+// descendant combinators are inlined, other rules and at-rules like @media
+// stay in the style element.
+func InlineCSS(document string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", errors.Wrap(err, "could not parse html")
+	}
+
+	var styles []*html.Node
+	walkNodes(doc, func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Style {
+			styles = append(styles, n)
+		}
+	})
+
+	rules := []cssRule{}
+	for _, style := range styles {
+		css := ""
+		for c := style.FirstChild; c != nil; c = c.NextSibling {
+			css += c.Data
+		}
+
+		parsed, rest := parseCSS(css, len(rules))
+		rules = append(rules, parsed...)
+
+		if strings.TrimSpace(rest) == "" {
+			style.Parent.RemoveChild(style)
+		} else {
+			for style.FirstChild != nil {
+				style.RemoveChild(style.FirstChild)
+			}
+			style.AppendChild(&html.Node{Type: html.TextNode, Data: rest})
+		}
+	}
+
+	if len(rules) == 0 {
+		return document, nil
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].specificity != rules[j].specificity {
+			return rules[i].specificity < rules[j].specificity
+		}
+		return rules[i].order < rules[j].order
+	})
+
+	walkNodes(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+
+		decls := []cssDecl{}
+		for _, rule := range rules {
+			if matches(n, rule.selector) {
+				decls = append(decls, rule.decls...)
+			}
+		}
+		if len(decls) == 0 {
+			return
+		}
+
+		// existing inline styles win over rules
+		for i, attr := range n.Attr {
+			if attr.Key == "style" {
+				decls = append(decls, parseDecls(attr.Val)...)
+				n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+				break
+			}
+		}
+		n.Attr = append(n.Attr, html.Attribute{Key: "style", Val: formatDecls(decls)})
+	})
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return "", errors.Wrap(err, "could not render html")
+	}
+
+	return buf.String(), nil
+}
+
+func walkNodes(n *html.Node, fn func(n *html.Node)) {
+	for c := n.FirstChild; c != nil; {
+		// fn can remove c
+		next := c.NextSibling
+		fn(c)
+		if c.Parent != nil {
+			walkNodes(c, fn)
+		}
+		c = next
+	}
+}
+
+// parseCSS returns rules that can be inlined and css that has to stay in
+// the style element.
+func parseCSS(css string, order int) ([]cssRule, string) {
+	css = cssComment.ReplaceAllString(css, "")
+
+	rules := []cssRule{}
+	rest := strings.Builder{}
+
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			break
+		}
+
+		if strings.HasPrefix(css, "@") {
+			end := atRuleEnd(css)
+			rest.WriteString(css[:end] + "\n")
+			css = css[end:]
+			continue
+		}
+
+		start := strings.Index(css, "{")
+		end := strings.Index(css, "}")
+		if start < 0 || end < start {
+			rest.WriteString(css)
+			break
+		}
+		selectors, body := css[:start], css[start+1:end]
+		css = css[end+1:]
+
+		decls := parseDecls(body)
+		for _, selector := range strings.Split(selectors, ",") {
+			selector = strings.TrimSpace(selector)
+			compounds, specificity, ok := parseSelector(selector)
+			if !ok || hasImportant(decls) {
+				rest.WriteString(selector + " {" + body + "}\n")
+				continue
+			}
+			rules = append(rules, cssRule{
+				selector:    compounds,
+				specificity: specificity,
+				order:       order,
+				decls:       decls,
+			})
+			order++
+		}
+	}
+
+	return rules, rest.String()
+}
+
+// atRuleEnd returns end of at-rule, either at ; or after its block.
+func atRuleEnd(css string) int {
+	depth := 0
+	for i, c := range css {
+		switch c {
+		case ';':
+			if depth == 0 {
+				return i + 1
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(css)
+}
+
+func parseSelector(selector string) ([]cssSelector, int, bool) {
+	if selector == "" || strings.ContainsAny(selector, ":[>+~*") {
+		return nil, 0, false
+	}
+
+	compounds := []cssSelector{}
+	specificity := 0
+	for _, part := range strings.Fields(selector) {
+		compound := cssSelector{}
+		for part != "" {
+			end := strings.IndexAny(part[1:], ".#") + 1
+			if end == 0 {
+				end = len(part)
+			}
+			token := part[:end]
+			part = part[end:]
+
+			switch token[0] {
+			case '#':
+				compound.id = token[1:]
+				specificity += 10000
+			case '.':
+				compound.classes = append(compound.classes, token[1:])
+				specificity += 100
+			default:
+				compound.tag = strings.ToLower(token)
+				specificity++
+			}
+		}
+		compounds = append(compounds, compound)
+	}
+
+	return compounds, specificity, true
+}
+
+func parseDecls(body string) []cssDecl {
+	decls := []cssDecl{}
+	for _, decl := range strings.Split(body, ";") {
+		parts := strings.SplitN(decl, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		property, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if property == "" || value == "" {
+			continue
+		}
+		decls = append(decls, cssDecl{strings.ToLower(property), value})
+	}
+	return decls
+}
+
+func hasImportant(decls []cssDecl) bool {
+	for _, decl := range decls {
+		if strings.Contains(decl.value, "!important") {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDecls keeps the last value of every property, in order of first
+// appearance.
+func formatDecls(decls []cssDecl) string {
+	values := map[string]string{}
+	properties := []string{}
+	for _, decl := range decls {
+		if _, ok := values[decl.property]; !ok {
+			properties = append(properties, decl.property)
+		}
+		values[decl.property] = decl.value
+	}
+
+	parts := make([]string, len(properties))
+	for i, property := range properties {
+		parts[i] = property + ": " + values[property]
+	}
+	return strings.Join(parts, "; ")
+}
+
+// matches reports whether n matches the last compound and its ancestors
+// match the others in order.
+func matches(n *html.Node, compounds []cssSelector) bool {
+	last := len(compounds) - 1
+	if !matchesCompound(n, compounds[last]) {
+		return false
+	}
+
+	i := last - 1
+	for p := n.Parent; p != nil && i >= 0; p = p.Parent {
+		if p.Type == html.ElementNode && matchesCompound(p, compounds[i]) {
+			i--
+		}
+	}
+	return i < 0
+}
+
+func matchesCompound(n *html.Node, compound cssSelector) bool {
+	if compound.tag != "" && compound.tag != n.Data {
+		return false
+	}
+
+	id, class := "", ""
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "id":
+			id = attr.Val
+		case "class":
+			class = attr.Val
+		}
+	}
+
+	if compound.id != "" && compound.id != id {
+		return false
+	}
+	classes := strings.Fields(class)
+	for _, c := range compound.classes {
+		found := false
+		for _, have := range classes {
+			if have == c {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInlineCSS(t *testing.T) {
+	document := `<html><head><style>
+/* comment */
+p { color: red; margin: 0 }
+.note { color: blue }
+p#intro { color: green }
+div .note b { font-weight: bold }
+a:hover { color: black }
+@media (max-width: 600px) { p { margin: 1em } }
+</style></head><body>
+<p id="intro" class="note">intro</p>
+<p class="note" style="margin: 2px">note</p>
+<div><span class="note"><b>bold</b></span></div>
+<b>plain</b>
+</body></html>`
+
+	out, err := InlineCSS(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`<p id="intro" class="note" style="color: green; margin: 0">intro</p>`,
+		`<p class="note" style="color: blue; margin: 2px">note</p>`,
+		`<span class="note" style="color: blue"><b style="font-weight: bold">bold</b></span>`,
+		`<b>plain</b>`,
+		`a:hover { color: black }`,
+		`@media (max-width: 600px) { p { margin: 1em } }`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "comment") {
+		t.Errorf("output contains comment:\n%s", out)
+	}
+}
+
+func TestInlineCSSRemovesInlinedStyle(t *testing.T) {
+	out, err := InlineCSS(`<html><head><style>p { color: red }</style></head><body><p>text</p></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<html><head></head><body><p style="color: red">text</p></body></html>`
+	if out != want {
+		t.Errorf("got %s, want %s", out, want)
+	}
+}
+
+func TestInlineCSSImportant(t *testing.T) {
+	out, err := InlineCSS(`<html><head><style>p { color: red !important }</style></head><body><p>text</p></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(out, `style="`) || !strings.Contains(out, "p { color: red !important }") {
+		t.Errorf("important rule was inlined:\n%s", out)
+	}
+}
@@ -0,0 +1,226 @@
+package render
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/matematik7/gongo"
+	"github.com/pkg/errors"
+)
+
+const (
+	mimeHTML        = "text/html"
+	mimeJSON        = "application/json"
+	mimeXML         = "application/xml"
+	mimeProblemJSON = "application/problem+json"
+	mimeProblemXML  = "application/problem+xml"
+)
+
+// problem is an RFC 7807 problem document.
+type problem struct {
+	XMLName  xml.Name `json:"-" xml:"urn:ietf:rfc:7807 problem"`
+	Type     string   `json:"type" xml:"type"`
+	Title    string   `json:"title" xml:"title"`
+	Status   int      `json:"status" xml:"status"`
+	Detail   string   `json:"detail,omitempty" xml:"detail,omitempty"`
+	Instance string   `json:"instance,omitempty" xml:"instance,omitempty"`
+
+	// Errors is an extension member with errors of invalid fields
+	Errors problemErrors `json:"errors,omitempty" xml:"errors,omitempty"`
+}
+
+type problemErrors map[string]string
+
+// MarshalXML encodes errors as <error field="name">msg</error> elements,
+// sorted by field, because encoding/xml does not support maps.
+func (pe problemErrors) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	fields := make([]string, 0, len(pe))
+	for field := range pe {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		element := xml.StartElement{
+			Name: xml.Name{Local: "error"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "field"}, Value: field}},
+		}
+		if err := e.EncodeElement(pe[field], element); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func newProblem(req *http.Request, status int, title, detail string, fields map[string]string) problem {
+	return problem{
+		Type:     "about:blank",
+		Title:    title,
+		Status:   status,
+		Detail:   detail,
+		Instance: req.URL.Path,
+		Errors:   fields,
+	}
+}
+
+// MarshalXML encodes context as an element per key, sorted by key, because
+// encoding/xml does not support maps.
+func (ctx Context) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if start.Name.Local == "" {
+		start.Name.Local = "context"
+	}
+
+	keys := make([]string, 0, len(ctx))
+	for key := range ctx {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := e.EncodeElement(ctx[key], xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (r *Render) JSON(w http.ResponseWriter, req *http.Request, v interface{}) {
+	if err := writeJSON(w, http.StatusOK, mimeJSON, v); err != nil {
+		r.Error(w, req, err)
+	}
+}
+
+func (r *Render) XML(w http.ResponseWriter, req *http.Request, v interface{}) {
+	if err := writeXML(w, http.StatusOK, mimeXML, v); err != nil {
+		r.Error(w, req, err)
+	}
+}
+
+// Negotiate renders template name for browsers and ctx as JSON or XML for
+// clients that prefer those.
+func (r *Render) Negotiate(w http.ResponseWriter, req *http.Request, name string, ctx Context) {
+	switch Negotiate(req, mimeHTML, mimeJSON, mimeXML) {
+	case mimeJSON:
+		r.JSON(w, req, ctx)
+	case mimeXML:
+		r.XML(w, req, ctx)
+	default:
+		r.Template(w, req, name, ctx)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, contentType string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "could not encode json")
+	}
+
+	return write(w, status, contentType, data)
+}
+
+func writeXML(w http.ResponseWriter, status int, contentType string, v interface{}) error {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "could not encode xml")
+	}
+
+	return write(w, status, contentType, append([]byte(xml.Header), data...))
+}
+
+func write(w http.ResponseWriter, status int, contentType string, data []byte) error {
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.WriteHeader(status)
+
+	// write errors are network errors, which are not worth reporting
+	w.Write(data)
+
+	return nil
+}
+
+// Negotiate returns the offer that best matches Accept header of req. First
+// offer is returned when nothing matches or Accept header is missing.
+func Negotiate(req *http.Request, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+
+	accepted := parseAccept(req.Header.Get("Accept"))
+	if len(accepted) == 0 {
+		return offers[0]
+	}
+
+	best := offers[0]
+	bestQ := 0.0
+	for _, offer := range offers {
+		// the most specific matching range determines quality of the offer
+		q := 0.0
+		specificity := -1
+		for _, a := range accepted {
+			if s := a.matches(offer); s > specificity {
+				specificity = s
+				q = a.q
+			}
+		}
+
+		if q > bestQ {
+			best = offer
+			bestQ = q
+		}
+	}
+
+	return best
+}
+
+type acceptRange struct {
+	mediaType string
+	subType   string
+	q         float64
+}
+
+// matches returns how specifically the range matches mime type, or -1 if
+// it does not match at all.
+func (a acceptRange) matches(mime string) int {
+	parts := strings.SplitN(mime, "/", 2)
+	if len(parts) != 2 {
+		return -1
+	}
+
+	switch {
+	case a.mediaType == "*" && a.subType == "*":
+		return 0
+	case a.mediaType == parts[0] && a.subType == "*":
+		return 1
+	case a.mediaType == parts[0] && a.subType == parts[1]:
+		return 2
+	}
+
+	return -1
+}
+
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+
+	for _, accepted := range gongo.ParseAccept(header) {
+		types := strings.SplitN(accepted.Value, "/", 2)
+		if len(types) != 2 {
+			continue
+		}
+
+		ranges = append(ranges, acceptRange{
+			mediaType: types[0],
+			subType:   types[1],
+			q:         accepted.Q,
+		})
+	}
+
+	return ranges
+}
@@ -0,0 +1,75 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", mimeHTML},
+		{"text/html,application/xhtml+xml,*/*;q=0.8", mimeHTML},
+		{"application/json", mimeJSON},
+		{"application/xml;q=0.9, application/json;q=0.8", mimeXML},
+		{"application/*", mimeJSON},
+		{"*/*", mimeHTML},
+		{"text/*;q=0.1, application/json;q=0.5", mimeJSON},
+		{"*/*, text/html;q=0", mimeJSON},
+		{"image/png", mimeHTML},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		if got := Negotiate(req, mimeHTML, mimeJSON, mimeXML); got != test.want {
+			t.Errorf("%q: got %s, want %s", test.accept, got, test.want)
+		}
+	}
+}
+
+func TestValidationErrorDocuments(t *testing.T) {
+	r := newRender(t, fstest.MapFS{})
+	err := ValidationError{Fields: map[string]string{"Name": "This field is required.", "Email": "Enter a valid value."}}
+
+	tests := []struct {
+		accept      string
+		contentType string
+		body        []string
+	}{
+		{"application/json", mimeProblemJSON, []string{`"errors":{"Email":"Enter a valid value.","Name":"This field is required."}`}},
+		{"application/xml", mimeProblemXML, []string{`<errors><error field="Email">Enter a valid value.</error><error field="Name">This field is required.</error></errors>`}},
+		{"text/html", mimeHTML, []string{"<li>Email: Enter a valid value.</li>", "<li>Name: This field is required.</li>"}},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/users", nil)
+		req.Header.Set("Accept", test.accept)
+
+		w := httptest.NewRecorder()
+		r.HandleError(w, req, err)
+
+		if w.Code != http.StatusUnprocessableEntity || !strings.HasPrefix(w.Header().Get("Content-Type"), test.contentType) {
+			t.Errorf("%s: got %d %s", test.accept, w.Code, w.Header().Get("Content-Type"))
+		}
+		for _, body := range test.body {
+			if !strings.Contains(w.Body.String(), body) {
+				t.Errorf("%s: body does not contain %s:\n%s", test.accept, body, w.Body.String())
+			}
+		}
+	}
+
+	// documents without field errors have no errors member
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+	r.HandleError(w, req, NotFoundError{})
+	if strings.Contains(w.Body.String(), "errors") {
+		t.Errorf("got errors in %s", w.Body.String())
+	}
+}
@@ -0,0 +1,167 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+
+	"github.com/flosch/pongo2"
+	"github.com/pkg/errors"
+)
+
+// OutputContextFunc adds values to context of templates rendered without
+// a request, c is the context passed to RenderToWriter.
+type OutputContextFunc func(c context.Context, ctx Context)
+
+// Email is rendered email with optional text and html parts.
+type Email struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// AddOutputContextFunc adds f to context funcs of RenderToWriter, request
+// context funcs are not used there.
+func (r *Render) AddOutputContextFunc(f OutputContextFunc) {
+	r.outputContextFuncs = append(r.outputContextFuncs, f)
+}
+
+// RenderToWriter executes template name into w without a request, for
+// emails, documents or background jobs.
+func (r *Render) RenderToWriter(c context.Context, w io.Writer, name string, ctx Context) error {
+	if ctx == nil {
+		ctx = Context{}
+	}
+	for _, cf := range r.outputContextFuncs {
+		cf(c, ctx)
+	}
+
+	t, err := r.templateSet.FromCache(name)
+	if err != nil {
+		return errors.Wrapf(err, "could not get template %s", name)
+	}
+
+	if err := t.ExecuteWriter(pongo2.Context(ctx), w); err != nil {
+		return errors.Wrapf(err, "could not execute template %s", name)
+	}
+
+	return nil
+}
+
+func (r *Render) RenderToString(c context.Context, name string, ctx Context) (string, error) {
+	var buf bytes.Buffer
+	if err := r.RenderToWriter(c, &buf, name, ctx); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RenderEmail renders email from templates name.subject.txt, name.txt and
+// name.html, at least one of the body templates has to exist. Email
+// templates can extend emails/layout.html and emails/layout.txt. Styles of
+// the html part are inlined, because many email clients ignore them.
+func (r *Render) RenderEmail(c context.Context, name string, ctx Context) (*Email, error) {
+	if ctx == nil {
+		ctx = Context{}
+	}
+	email := &Email{}
+
+	if r.loader.Exists(name + ".subject.txt") {
+		subject, err := r.RenderToString(c, name+".subject.txt", ctx)
+		if err != nil {
+			return nil, err
+		}
+		// subject is plain text, but templates escape html
+		email.Subject = html.UnescapeString(strings.Join(strings.Fields(subject), " "))
+		ctx["subject"] = email.Subject
+	}
+
+	if r.loader.Exists(name + ".txt") {
+		text, err := r.RenderToString(c, name+".txt", ctx)
+		if err != nil {
+			return nil, err
+		}
+		email.Text = strings.TrimSpace(text) + "\n"
+	}
+
+	if r.loader.Exists(name + ".html") {
+		body, err := r.RenderToString(c, name+".html", ctx)
+		if err != nil {
+			return nil, err
+		}
+		email.HTML, err = InlineCSS(body)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not inline css of %s.html", name)
+		}
+	}
+
+	if email.Text == "" && email.HTML == "" {
+		return nil, errors.Errorf("email templates %s.txt and %s.html not found", name, name)
+	}
+
+	return email, nil
+}
+
+// WriteBody writes body of the email to w and returns headers describing
+// it, the body is multipart/alternative if it has both text and html part.
+func (e *Email) WriteBody(w io.Writer) (textproto.MIMEHeader, error) {
+	if e.HTML == "" || e.Text == "" {
+		header := textproto.MIMEHeader{
+			"Content-Type":              {"text/plain; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		}
+		body := e.Text
+		if e.HTML != "" {
+			header.Set("Content-Type", "text/html; charset=utf-8")
+			body = e.HTML
+		}
+		if err := writeQuotedPrintable(w, body); err != nil {
+			return nil, err
+		}
+		return header, nil
+	}
+
+	mw := multipart.NewWriter(w)
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", e.Text},
+		{"text/html; charset=utf-8", e.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create email part")
+		}
+		if err := writeQuotedPrintable(pw, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, errors.Wrap(err, "could not close email body")
+	}
+
+	return textproto.MIMEHeader{
+		"Content-Type": {fmt.Sprintf("multipart/alternative; boundary=%q", mw.Boundary())},
+	}, nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qw, body); err != nil {
+		return errors.Wrap(err, "could not write email body")
+	}
+	if err := qw.Close(); err != nil {
+		return errors.Wrap(err, "could not write email body")
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+// Middleware recovers from panics in handlers, logs them with the stack
+// trace and renders the error page if nothing was written yet.
+func (r *Render) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, req.ProtoMajor)
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			msg := fmt.Sprintf("panic: %v", rec)
+			stack := string(debug.Stack())
+
+			r.logging.WithRequest(req).WithField("Stack", stack).Error(msg)
+
+			// headers were already sent, error page would only corrupt the response
+			if ww.Status() != 0 {
+				return
+			}
+
+			if !r.isProd {
+				msg += "\n\n" + stack
+			}
+			r.serverError(ww, req, msg)
+		}()
+
+		next.ServeHTTP(ww, req)
+	})
+}
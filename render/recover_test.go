@@ -0,0 +1,63 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMiddlewareRecovers(t *testing.T) {
+	r := newRender(t, fstest.MapFS{
+		"errors/500.html": {Data: []byte(`error page`)},
+	})
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		body    string
+	}{
+		{
+			name: "before write",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				panic("boom")
+			},
+			status: http.StatusInternalServerError,
+			body:   "error page",
+		},
+		{
+			name: "after partial write",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte("partial"))
+				panic("boom")
+			},
+			status: http.StatusAccepted,
+			body:   "partial",
+		},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.Middleware(test.handler).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+		if w.Code != test.status || strings.TrimSpace(w.Body.String()) != test.body {
+			t.Errorf("%s: got %d %q, want %d %q", test.name, w.Code, w.Body.String(), test.status, test.body)
+		}
+	}
+}
+
+func TestMiddlewareAbortHandler(t *testing.T) {
+	r := newRender(t, fstest.MapFS{})
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("got panic %v, want http.ErrAbortHandler", rec)
+		}
+	}()
+
+	r.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
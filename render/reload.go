@@ -0,0 +1,157 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/matematik7/gongo"
+	"github.com/pkg/errors"
+)
+
+// reloadInterval is how often templates are checked for changes in
+// development.
+var reloadInterval = time.Second
+
+// WalkTemplates calls fn for every file in dir of source, with name
+// relative to the root of source. Any http.FileSystem can be walked, other
+// sources have to support Readdir.
+func WalkTemplates(source Templates, dir string, fn func(name string, info os.FileInfo) error) error {
+	f, err := source.Open("/" + dir)
+	if err != nil {
+		return errors.Wrapf(err, "could not open %s", dir)
+	}
+	infos, err := f.Readdir(-1)
+	f.Close()
+	if err != nil {
+		return errors.Wrapf(err, "could not read dir %s", dir)
+	}
+
+	for _, info := range infos {
+		name := path.Join(dir, info.Name())
+		if info.IsDir() {
+			if err := WalkTemplates(source, name, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := fn(name, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Precompile parses all templates into the cache, so syntax errors are
+// reported at startup instead of on the first request.
+func (r *Render) Precompile() error {
+	names, err := r.TemplateNames()
+	if err != nil {
+		return errors.Wrap(err, "could not list templates")
+	}
+
+	var errs gongo.MultiError
+	for _, name := range names {
+		if _, err := r.templateSet.FromCache(name); err != nil {
+			errs = append(errs, errors.Wrapf(err, "could not compile template %s", name))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// PostConfigure precompiles templates in production, after every component
+// is configured, so templates added by dependents in Configure are
+// included.
+func (r *Render) PostConfigure(app *gongo.App) error {
+	if !r.isProd {
+		return nil
+	}
+
+	if err := r.Precompile(); err != nil {
+		return errors.Wrap(err, "could not precompile templates")
+	}
+
+	return nil
+}
+
+// Start watches templates for changes in development.
+func (r *Render) Start(ctx context.Context) error {
+	if r.isProd {
+		return nil
+	}
+
+	r.stopReload = make(chan struct{})
+	r.reloadDone = make(chan struct{})
+
+	// taken before returning, so changes right after start are noticed
+	last := r.templatesVersion()
+	go func() {
+		defer close(r.reloadDone)
+
+		ticker := time.NewTicker(reloadInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stopReload:
+				return
+			case <-ticker.C:
+				if current := r.templatesVersion(); current != last {
+					r.logging.Logger().Debug("templates changed, clearing cache")
+					r.templateSet.CleanCache()
+					last = current
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (r *Render) Stop(ctx context.Context) error {
+	if r.stopReload == nil {
+		return nil
+	}
+
+	close(r.stopReload)
+
+	select {
+	case <-r.reloadDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// templatesVersion summarizes names, sizes and modification times of all
+// templates, so any change produces a different value.
+func (r *Render) templatesVersion() string {
+	var version strings.Builder
+	for _, source := range r.loader.sources() {
+		WalkTemplates(source, "", func(name string, info os.FileInfo) error {
+			fmt.Fprintf(&version, "%s %d %d\n", name, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+	}
+	return version.String()
+}
+
+// listable reports if the root of source can be listed, sources that only
+// support Open are skipped when listing templates.
+func listable(source Templates) bool {
+	f, err := source.Open("/")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	_, err = f.Readdir(1)
+	return err == nil || err == io.EOF
+}
@@ -0,0 +1,120 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// openOnly is a template source that can not be listed.
+type openOnly struct {
+	http.FileSystem
+}
+
+func (o openOnly) Open(name string) (http.File, error) {
+	f, err := o.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return unlistable{f}, nil
+}
+
+type unlistable struct {
+	http.File
+}
+
+func (unlistable) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, errors.New("listing is not supported")
+}
+
+func TestPrecompile(t *testing.T) {
+	r := New(true)
+	r.AddTemplates(http.FS(fstest.MapFS{
+		"valid.html":  {Data: []byte(`{{ value }}`)},
+		"broken.html": {Data: []byte(`{% if %}`)},
+	}))
+
+	err := configure(r)
+	if err == nil || !strings.Contains(err.Error(), "could not compile template broken.html") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestPrecompileUnlistable(t *testing.T) {
+	r := New(true)
+	r.AddTemplates(openOnly{http.FS(fstest.MapFS{
+		"page.html": {Data: []byte(`page`)},
+	})})
+	if err := configure(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, err := r.RenderToString(context.Background(), "page.html", nil); err != nil || out != "page" {
+		t.Errorf("got %q, %v", out, err)
+	}
+}
+
+// lockedFS allows changing templates while they are watched.
+type lockedFS struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+func (l *lockedFS) Open(name string) (http.File, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return http.FS(l.files).Open(name)
+}
+
+func (l *lockedFS) set(name, data string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.files[name] = &fstest.MapFile{Data: []byte(data), ModTime: time.Now()}
+}
+
+func TestReload(t *testing.T) {
+	defer func(interval time.Duration) {
+		reloadInterval = interval
+	}(reloadInterval)
+	reloadInterval = time.Millisecond
+
+	templates := &lockedFS{files: fstest.MapFS{}}
+	templates.set("page.html", "first")
+
+	r := New(false)
+	r.AddTemplates(templates)
+	if err := configure(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop(context.Background())
+
+	if out, err := r.RenderToString(context.Background(), "page.html", nil); err != nil || out != "first" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+
+	templates.set("page.html", "second")
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		out, err := r.RenderToString(context.Background(), "page.html", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out == "second" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("template was not reloaded, got %q", out)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
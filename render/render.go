@@ -1,71 +1,165 @@
 package render
 
 import (
+	"bytes"
 	"context"
+	"embed"
 	"io"
-	"net"
+	"io/fs"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
+	"sort"
 	"strings"
 
 	"github.com/flosch/pongo2"
-	"github.com/go-chi/chi/middleware"
 	"github.com/gorilla/sessions"
 	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/logging"
 	"github.com/pkg/errors"
-	"github.com/sirupsen/logrus"
 )
 
 type Templates interface {
 	Open(name string) (http.File, error)
 }
 
+//go:embed templates
+var defaultTemplates embed.FS
+
 type Context map[string]interface{}
 
 type ContextFunc func(r *http.Request, ctx Context)
 
+// Translator is implemented by a component that translates messages
+// rendered by Render to the locale of the request.
+type Translator interface {
+	Translate(ctx context.Context, msg string) string
+}
+
+// Preprocessor rewrites template source before it is parsed.
+type Preprocessor func(data []byte) []byte
+
 type templateLoader struct {
 	templateSources []Templates
+	defaults        []Templates
+	preprocessors   []Preprocessor
 }
 
 func (tl *templateLoader) Add(t Templates) {
 	tl.templateSources = append(tl.templateSources, t)
 }
 
+func (tl *templateLoader) AddDefault(t Templates) {
+	tl.defaults = append(tl.defaults, t)
+}
+
+// sources returns app sources first, so they override the defaults.
+func (tl templateLoader) sources() []Templates {
+	sources := make([]Templates, 0, len(tl.templateSources)+len(tl.defaults))
+	sources = append(sources, tl.templateSources...)
+	return append(sources, tl.defaults...)
+}
+
+// Abs resolves names starting with ./ or ../ relative to the including
+// template, other names are relative to the root of template sources.
 func (tl templateLoader) Abs(base, name string) string {
-	return name
+	if base != "" && (strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../")) {
+		name = path.Join(path.Dir(base), name)
+	}
+	// names can not escape the root of template sources
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
 }
 
-func (tl templateLoader) Get(path string) (io.Reader, error) {
-	for _, source := range tl.templateSources {
-		f, err := source.Open(path)
+func (tl templateLoader) Get(name string) (io.Reader, error) {
+	for _, source := range tl.sources() {
+		f, err := source.Open(name)
 		if err != nil {
 			if os.IsNotExist(err) {
 				continue
 			}
 			return nil, err
 		}
+		defer f.Close()
+
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read template %s", name)
+		}
 
-		return f, nil
+		for _, preprocess := range tl.preprocessors {
+			data = preprocess(data)
+		}
+
+		return bytes.NewReader(data), nil
 	}
 
-	return nil, errors.Errorf("template %s not found", path)
+	return nil, errors.Errorf("template %s not found", name)
 }
 
-type FieldsProvider interface {
-	LoggerFields(context.Context) map[string]interface{}
+func (tl templateLoader) Exists(name string) bool {
+	for _, source := range tl.sources() {
+		f, err := source.Open(name)
+		if err != nil {
+			continue
+		}
+		info, err := f.Stat()
+		f.Close()
+		if err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns names of all templates from all sources that can be listed.
+func (tl templateLoader) List() ([]string, error) {
+	seen := make(map[string]bool)
+	names := []string{}
+
+	for _, source := range tl.sources() {
+		if !listable(source) {
+			continue
+		}
+
+		err := WalkTemplates(source, "", func(name string, info os.FileInfo) error {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
 }
 
 type Render struct {
+	// ETags enables buffered rendering of successful GET requests, with
+	// ETag computed from the output and 304 responses to If-None-Match.
+	ETags bool
+
 	isProd bool
 
-	log             *logrus.Logger
-	fieldsProviders []FieldsProvider
-	store           sessions.Store
+	logging    *logging.Logging
+	store      sessions.Store
+	translator Translator
+
+	errorTemplates []errorTemplates
 
 	templateSet  *pongo2.TemplateSet
 	loader       *templateLoader
 	contextFuncs []ContextFunc
+
+	outputContextFuncs []OutputContextFunc
+
+	stopReload chan struct{}
+	reloadDone chan struct{}
 }
 
 type Request struct {
@@ -81,7 +175,15 @@ func New(isProd bool) *Render {
 		loader:      loader,
 	}
 
-	r.templateSet.Debug = !isProd
+	// templates are always cached, in development the cache is cleared
+	// when templates change (see Start)
+	r.templateSet.Debug = false
+
+	templates, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		panic(err)
+	}
+	r.AddDefaultTemplates(http.FS(templates))
 
 	r.AddContextFunc(func(req *http.Request, ctx Context) {
 		ctx["request"] = Request{
@@ -93,16 +195,23 @@ func New(isProd bool) *Render {
 	return r
 }
 
-func (r *Render) Configure(app gongo.App) error {
-	r.log = app["Log"].(*logrus.Logger)
+func (r *Render) Dependencies() []string {
+	return []string{"Logging", "Store"}
+}
 
-	for _, component := range app {
-		if fieldsProvider, ok := component.(FieldsProvider); ok {
-			r.fieldsProviders = append(r.fieldsProviders, fieldsProvider)
-		}
+func (r *Render) Configure(app *gongo.App) error {
+	if err := app.Lookup("Logging", &r.logging); err != nil {
+		return err
+	}
+	if err := app.Lookup("Store", &r.store); err != nil {
+		return err
 	}
 
-	r.store = app["Store"].(sessions.Store)
+	for _, name := range app.Names() {
+		if translator, ok := app.Get(name).(Translator); ok {
+			r.translator = translator
+		}
+	}
 
 	return nil
 }
@@ -111,113 +220,113 @@ func (r *Render) AddTemplates(t Templates) {
 	r.loader.Add(t)
 }
 
-func (r *Render) AddContextFunc(f ContextFunc) {
-	r.contextFuncs = append(r.contextFuncs, f)
+// AddDefaultTemplates adds templates that are used only when none of the
+// sources added with AddTemplates has a template with the same name.
+func (r *Render) AddDefaultTemplates(t Templates) {
+	r.loader.AddDefault(t)
 }
 
-func (r *Render) AddFlash(w http.ResponseWriter, req *http.Request, flash interface{}) error {
-	session, err := r.store.Get(req, "render")
-	if err != nil {
-		return errors.Wrap(err, "could not get store")
-	}
-
-	session.AddFlash(flash)
+// AddPreprocessor adds f that rewrites every template before it is parsed,
+// it must be added before templates are rendered.
+func (r *Render) AddPreprocessor(f Preprocessor) {
+	r.loader.preprocessors = append(r.loader.preprocessors, f)
+}
 
-	if err := session.Save(req, w); err != nil {
-		return errors.Wrap(err, "could not save session")
-	}
+// TemplateNames returns names of all available templates.
+func (r *Render) TemplateNames() ([]string, error) {
+	return r.loader.List()
+}
 
-	return nil
+func (r *Render) AddContextFunc(f ContextFunc) {
+	r.contextFuncs = append(r.contextFuncs, f)
 }
 
-func (r *Render) renderTemplate(w http.ResponseWriter, req *http.Request, name string, ctx Context) error {
-	if strings.HasSuffix(name, ".html") {
-		w.Header().Set("Content-Type", "text/html")
-	}
+func (r *Render) renderTemplate(w http.ResponseWriter, req *http.Request, status int, name string, ctx Context) error {
 	for _, cf := range r.contextFuncs {
 		cf(req, ctx)
 	}
 
-	session, err := r.store.Get(req, "render")
-	if err != nil {
-		return errors.Wrap(err, "could not get render store")
-	}
-	ctx["flashes"] = session.Flashes()
-	err = session.Save(req, w)
+	flashes, err := r.Flashes(w, req)
 	if err != nil {
-		return errors.Wrap(err, "could not save render session")
+		return err
 	}
+	ctx["flashes"] = flashes
 
 	t, err := r.templateSet.FromCache(name)
 	if err != nil {
 		return errors.Wrapf(err, "could not get template %s", name)
 	}
-	err = t.ExecuteWriter(pongo2.Context(ctx), w)
+
+	if strings.HasSuffix(name, ".html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+
+	// templates are rendered into a buffer, so the status is only written
+	// on success and callers can still render an error page
+	data, err := t.ExecuteBytes(pongo2.Context(ctx))
 	if err != nil {
-		// Do not log network errors (there were a lot of write on closed socket errors)
-		if _, ok := err.(*net.OpError); !ok {
-			return errors.Wrapf(err, "could not execute template %s", name)
-		}
+		return errors.Wrapf(err, "could not execute template %s", name)
+	}
+
+	if r.ETags && status == http.StatusOK && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+		writeETag(w, req, data)
+		return nil
 	}
 
+	w.WriteHeader(status)
+
+	// write errors are network errors, which are not worth reporting
+	w.Write(data)
+
 	return nil
 }
 
 func (r *Render) Template(w http.ResponseWriter, req *http.Request, name string, ctx Context) {
-	err := r.renderTemplate(w, req, name, ctx)
+	err := r.renderTemplate(w, req, http.StatusOK, name, ctx)
 	if err != nil {
 		r.Error(w, req, err)
 	}
 }
 
 func (r *Render) NotFound(w http.ResponseWriter, req *http.Request) {
-	w.WriteHeader(http.StatusNotFound)
-	r.Template(w, req, "error.html", Context{
-		"title": "Not Found",
-		"msg":   "This is not the web page you are looking for.",
-	})
+	r.HTTPError(w, req, http.StatusNotFound, nil)
 }
 
 func (r *Render) MethodNotAllowed(w http.ResponseWriter, req *http.Request) {
-	w.WriteHeader(http.StatusMethodNotAllowed)
-	r.Template(w, req, "error.html", Context{
-		"title": "Method Not Allowed",
-		"msg":   "Your position's correct, except... not this method.",
-	})
+	r.HTTPError(w, req, http.StatusMethodNotAllowed, nil)
 }
 
 func (r *Render) Forbidden(w http.ResponseWriter, req *http.Request) {
-	w.WriteHeader(http.StatusForbidden)
-	r.Template(w, req, "error.html", Context{
-		"title": "Forbidden",
-		"msg":   "The Force is not strong with you.",
-	})
+	r.HTTPError(w, req, http.StatusForbidden, nil)
 }
 
 func (r *Render) Error(w http.ResponseWriter, req *http.Request, err error) {
 	msg := err.Error()
 
-	fields := logrus.Fields{
-		"RequestId": middleware.GetReqID(req.Context()),
-		"URL":       req.URL.String(),
-	}
-	// TODO: separate this to logger package and use fields from main.go
-	for _, fieldsProvider := range r.fieldsProviders {
-		for k, v := range fieldsProvider.LoggerFields(req.Context()) {
-			fields[k] = v
-		}
-	}
+	r.logging.WithRequest(req).Error(msg)
 
-	r.log.WithFields(fields).Error(msg)
+	r.serverError(w, req, msg)
+}
 
+func (r *Render) serverError(w http.ResponseWriter, req *http.Request, msg string) {
 	if r.isProd {
-		msg = "Sorry, something went wrong."
+		msg = r.translate(req, errorMessages[http.StatusInternalServerError])
 	}
 
-	w.WriteHeader(http.StatusInternalServerError)
-	// do not handle error, showing error template is best effort
-	r.renderTemplate(w, req, "error.html", Context{
-		"title": "Server Error",
-		"msg":   msg,
+	// showing error page is best effort, fall back to plain text
+	err := r.renderError(w, req, errorPage{
+		status: http.StatusInternalServerError,
+		msg:    msg,
 	})
+	if err != nil {
+		r.logging.WithRequest(req).Error(err.Error())
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
+
+func (r *Render) translate(req *http.Request, msg string) string {
+	if r.translator == nil {
+		return msg
+	}
+	return r.translator.Translate(req.Context(), msg)
 }
@@ -0,0 +1,122 @@
+package render
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gorilla/sessions"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/logging"
+)
+
+func newRender(t *testing.T, templates fstest.MapFS) *Render {
+	r := New(false)
+	r.AddTemplates(http.FS(templates))
+	if err := configure(r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// configure configures r in an app with its dependencies.
+func configure(r *Render) error {
+	app := gongo.New()
+	app.Register("Logging", logging.New(false))
+	app.Register("Store", sessions.NewCookieStore([]byte("secret")))
+	app.Register("Render", r)
+	return app.Configure()
+}
+
+func TestHTTPErrorTemplateFails(t *testing.T) {
+	r := newRender(t, fstest.MapFS{
+		"errors/404.html": {Data: []byte(`{{ msg }} {{ status() }}`)},
+	})
+
+	w := httptest.NewRecorder()
+	r.HTTPError(w, httptest.NewRequest("GET", "/", nil), http.StatusNotFound, nil)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	r := newRender(t, fstest.MapFS{
+		"errors/409.html": {Data: []byte(`{{ status }}: {{ msg }}`)},
+	})
+
+	w := httptest.NewRecorder()
+	r.HTTPError(w, httptest.NewRequest("GET", "/", nil), http.StatusConflict, ConflictError{Message: "Edited twice."})
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusConflict)
+	}
+	if body := w.Body.String(); body != "409: Edited twice." {
+		t.Errorf("got body %q", body)
+	}
+}
+
+func TestAbs(t *testing.T) {
+	tests := []struct {
+		base string
+		name string
+		want string
+	}{
+		{"", "index.html", "index.html"},
+		{"", "/index.html", "index.html"},
+		{"pages/index.html", "layout.html", "layout.html"},
+		{"pages/index.html", "./partial.html", "pages/partial.html"},
+		{"pages/admin/index.html", "../layout.html", "pages/layout.html"},
+		{"", "./partial.html", "partial.html"},
+		{"", "../../secret.html", "secret.html"},
+		{"pages/index.html", "../../secret.html", "secret.html"},
+	}
+	for _, test := range tests {
+		if got := (templateLoader{}).Abs(test.base, test.name); got != test.want {
+			t.Errorf("Abs(%q, %q): got %q, want %q", test.base, test.name, got, test.want)
+		}
+	}
+}
+
+func TestRelativeTemplates(t *testing.T) {
+	r := newRender(t, fstest.MapFS{
+		"base.html":              {Data: []byte(`base:{% block content %}{% endblock %}`)},
+		"pages/layout.html":      {Data: []byte(`{% extends "../base.html" %}{% block content %}layout:{% block page %}{% endblock %}{% endblock %}`)},
+		"pages/partial.html":     {Data: []byte(`partial`)},
+		"pages/users/index.html": {Data: []byte(`{% extends "../layout.html" %}{% block page %}{% include "../partial.html" %}{% endblock %}`)},
+	})
+
+	out, err := r.RenderToString(context.Background(), "pages/users/index.html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "base:layout:partial" {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestErrorTemplate(t *testing.T) {
+	r := newRender(t, fstest.MapFS{
+		"admin/errors/404.html": {Data: []byte(`admin`)},
+		"errors/404.html":       {Data: []byte(`global`)},
+	})
+	r.AddErrorTemplates("/admin", "admin")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/admin", "admin/errors/404.html"},
+		{"/admin/users", "admin/errors/404.html"},
+		{"/administrator", "errors/404.html"},
+		{"/", "errors/404.html"},
+	}
+	for _, test := range tests {
+		if got := r.errorTemplate(httptest.NewRequest("GET", test.path, nil), http.StatusNotFound); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package scheduler
+
+import "time"
+
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// TaskLock makes sure only one instance of the app runs each tick of a
+// task.
+type TaskLock struct {
+	Name        string `gorm:"primary_key"`
+	Tick        time.Time
+	LockedUntil time.Time
+	LockedBy    string
+}
+
+// TaskRun is history of task runs.
+type TaskRun struct {
+	ID         uint      `gorm:"primary_key"`
+	Task       string    `gorm:"index"`
+	Tick       time.Time `gorm:"index"`
+	Instance   string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Status     string
+	Error      string `gorm:"type:text"`
+}
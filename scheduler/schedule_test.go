@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"x * * * *",
+		"@every",
+		"@every soon",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// wednesday
+	now := time.Date(2020, 1, 15, 10, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2020, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2020, 1, 16, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2020, 1, 15, 13, 0, 0, 0, time.UTC)},
+		{"0,20 11 * * *", time.Date(2020, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * mon", time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2020, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * MON-FRI", time.Date(2020, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// day of month or day of week, when both are restricted
+		{"0 0 20 * fri", time.Date(2020, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2020, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 1h", time.Date(2020, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@every 10m", time.Date(2020, 1, 15, 10, 40, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, test := range tests {
+		schedule, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+		if got := schedule.Next(now); !got.Equal(test.want) {
+			t.Errorf("%q: got %s, want %s", test.spec, got, test.want)
+		}
+	}
+}
+
+func TestNextLocation(t *testing.T) {
+	loc := time.FixedZone("CET", 3600)
+	schedule, err := Parse("0 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2020, 1, 15, 3, 0, 0, 0, loc)
+	want := time.Date(2020, 1, 16, 2, 0, 0, 0, loc)
+	if got := schedule.Next(now); !got.Equal(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,276 @@
+package scheduler
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/logging"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const DefaultTimeout = time.Hour
+
+type TaskFunc func(ctx context.Context) error
+
+type Task struct {
+	Name     string
+	Schedule Schedule
+	Func     TaskFunc
+
+	// Jitter delays every run by random duration up to Jitter, so tasks
+	// of many apps do not run at the same moment.
+	Jitter time.Duration
+	// Timeout cancels context of the run, the lock of the run is held at
+	// most this long. DefaultTimeout is used when it is zero.
+	Timeout time.Duration
+}
+
+type Scheduler struct {
+	db      *gorm.DB
+	logging *logging.Logging
+
+	instance string
+	tasks    map[string]*Task
+	names    []string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{
+		instance: gongo.InstanceID(),
+		tasks:    make(map[string]*Task),
+	}
+}
+
+func (s *Scheduler) Dependencies() []string {
+	return []string{"DB", "Logging"}
+}
+
+func (s *Scheduler) Configure(app *gongo.App) error {
+	if err := app.Lookup("DB", &s.db); err != nil {
+		return err
+	}
+	if err := app.Lookup("Logging", &s.logging); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Scheduler) Resources() []interface{} {
+	return []interface{}{
+		&TaskLock{},
+		&TaskRun{},
+	}
+}
+
+// Add registers task name that runs on spec, a cron expression or
+// @every <duration>. Tasks should be added while configuring the app.
+func (s *Scheduler) Add(name, spec string, fn TaskFunc) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return errors.Wrapf(err, "could not parse schedule of task %s", name)
+	}
+
+	return s.AddTask(Task{
+		Name:     name,
+		Schedule: schedule,
+		Func:     fn,
+	})
+}
+
+func (s *Scheduler) AddTask(task Task) error {
+	if task.Name == "" || task.Schedule == nil || task.Func == nil {
+		return errors.New("task needs name, schedule and func")
+	}
+	if _, ok := s.tasks[task.Name]; ok {
+		return errors.Errorf("task %s is already added", task.Name)
+	}
+	if task.Timeout <= 0 {
+		task.Timeout = DefaultTimeout
+	}
+
+	s.tasks[task.Name] = &task
+	s.names = append(s.names, task.Name)
+
+	return nil
+}
+
+func (s *Scheduler) Start(ctx context.Context) error {
+	var runCtx context.Context
+	runCtx, s.cancel = context.WithCancel(context.Background())
+
+	for _, name := range s.names {
+		task := s.tasks[name]
+		s.logging.WithContext(ctx).WithFields(logrus.Fields{
+			"Task": task.Name,
+			"Next": task.Schedule.Next(time.Now()),
+		}).Debug("task scheduled")
+
+		s.wg.Add(1)
+		go s.loop(runCtx, task)
+	}
+
+	return nil
+}
+
+// Stop cancels running tasks and waits for them to return until ctx is
+// done.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("tasks did not stop in time")
+	}
+}
+
+// loop runs task on its schedule. Runs happen one after another, ticks
+// missed while the task is running are skipped.
+func (s *Scheduler) loop(ctx context.Context, task *Task) {
+	defer s.wg.Done()
+
+	for {
+		tick := task.Schedule.Next(time.Now())
+		if tick.IsZero() {
+			s.logging.WithContext(ctx).WithField("Task", task.Name).Warn("task schedule has no next run")
+			return
+		}
+
+		delay := time.Until(tick)
+		if task.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(task.Jitter)))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.run(ctx, task, tick)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, task *Task, tick time.Time) {
+	ctx = logging.NewContext(ctx, map[string]interface{}{
+		"Task": task.Name,
+		"Tick": tick,
+	})
+	log := s.logging.WithContext(ctx)
+
+	acquired, err := s.lock(task, tick)
+	if err != nil {
+		log.WithError(err).Error("could not lock task")
+		return
+	}
+	if !acquired {
+		log.Debug("task run by another instance")
+		return
+	}
+	defer func() {
+		if err := s.unlock(task); err != nil {
+			log.WithError(err).Error("could not unlock task")
+		}
+	}()
+
+	run := &TaskRun{
+		Task:      task.Name,
+		Tick:      tick.UTC(),
+		Instance:  s.instance,
+		StartedAt: time.Now().UTC(),
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, task.Timeout)
+	err = gongo.SafeCall(func() error {
+		return task.Func(runCtx)
+	})
+	cancel()
+
+	run.FinishedAt = time.Now().UTC()
+	// fields added by the task are logged too
+	log = s.logging.WithContext(ctx).WithField("Latency", run.FinishedAt.Sub(run.StartedAt).String())
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+		log.WithError(err).Error("task failed")
+	} else {
+		run.Status = RunSucceeded
+		log.Info("task succeeded")
+	}
+
+	if err := s.db.Create(run).Error; err != nil {
+		log.WithError(err).Error("could not save task run")
+	}
+}
+
+// lock claims tick of task for this instance, it fails if the tick was
+// already run or another instance is still running the task.
+func (s *Scheduler) lock(task *Task, tick time.Time) (bool, error) {
+	now := time.Now().UTC()
+	tick = tick.UTC()
+
+	update := s.db.Model(&TaskLock{}).Where("name = ? AND tick < ? AND locked_until < ?", task.Name, tick, now).Updates(map[string]interface{}{
+		"tick":         tick,
+		"locked_until": now.Add(task.Timeout),
+		"locked_by":    s.instance,
+	})
+	if update.Error != nil {
+		return false, errors.Wrapf(update.Error, "could not update lock of task %s", task.Name)
+	}
+	if update.RowsAffected == 1 {
+		return true, nil
+	}
+
+	exists, err := s.lockExists(task)
+	if err != nil || exists {
+		return false, err
+	}
+
+	// first run of the task
+	err = s.db.Create(&TaskLock{
+		Name:        task.Name,
+		Tick:        tick,
+		LockedUntil: now.Add(task.Timeout),
+		LockedBy:    s.instance,
+	}).Error
+	if err != nil {
+		// creating fails if another instance was faster, other errors
+		// are reported
+		if exists, existsErr := s.lockExists(task); existsErr == nil && exists {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "could not create lock of task %s", task.Name)
+	}
+
+	return true, nil
+}
+
+func (s *Scheduler) lockExists(task *Task) (bool, error) {
+	var count int
+	if err := s.db.Model(&TaskLock{}).Where("name = ?", task.Name).Count(&count).Error; err != nil {
+		return false, errors.Wrapf(err, "could not find lock of task %s", task.Name)
+	}
+	return count > 0, nil
+}
+
+func (s *Scheduler) unlock(task *Task) error {
+	return s.db.Model(&TaskLock{}).Where("name = ? AND locked_by = ?", task.Name, s.instance).Update("locked_until", time.Now().UTC()).Error
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/matematik7/gongo/logging"
+)
+
+func newScheduler(DB *gorm.DB, instance string) *Scheduler {
+	s := New()
+	s.db = DB
+	s.instance = instance
+	return s
+}
+
+func TestLock(t *testing.T) {
+	DB, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Close()
+	DB.AutoMigrate(&TaskLock{}, &TaskRun{})
+
+	first := newScheduler(DB, "first")
+	second := newScheduler(DB, "second")
+	task := &Task{Name: "report", Timeout: time.Minute}
+	tick := time.Date(2020, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name      string
+		scheduler *Scheduler
+		tick      time.Time
+		unlock    bool
+		acquired  bool
+	}{
+		{"first run", first, tick, false, true},
+		{"same tick while running", second, tick, false, false},
+		{"next tick while running", second, tick.Add(time.Hour), true, false},
+		{"same tick after unlock", second, tick, false, false},
+		{"next tick after unlock", second, tick.Add(time.Hour), false, true},
+	}
+	for _, step := range steps {
+		acquired, err := step.scheduler.lock(task, step.tick)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if acquired != step.acquired {
+			t.Errorf("%s: got acquired %v, want %v", step.name, acquired, step.acquired)
+		}
+		if step.unlock {
+			if err := first.unlock(task); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestLockError(t *testing.T) {
+	DB, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Close()
+
+	// creating the lock fails for another reason than a lock created by
+	// another instance, which must be reported
+	err = DB.Exec(`CREATE TABLE task_locks (
+		name varchar(255) PRIMARY KEY,
+		tick datetime,
+		locked_until datetime,
+		locked_by varchar(255) CHECK (locked_by <> 'first')
+	)`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newScheduler(DB, "first")
+	if _, err := s.lock(&Task{Name: "report", Timeout: time.Minute}, time.Now()); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestRunRecoversPanic(t *testing.T) {
+	DB, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Close()
+	DB.AutoMigrate(&TaskLock{}, &TaskRun{})
+
+	s := newScheduler(DB, "first")
+	s.logging = logging.New(false)
+	task := &Task{
+		Name:    "panics",
+		Timeout: time.Minute,
+		Func: func(ctx context.Context) error {
+			panic("boom")
+		},
+	}
+	s.run(context.Background(), task, time.Now())
+
+	var run TaskRun
+	if err := DB.Where("task = ?", "panics").First(&run).Error; err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != RunFailed || run.Error != "panic: boom" {
+		t.Errorf("got status %s and error %q", run.Status, run.Error)
+	}
+}
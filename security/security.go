@@ -0,0 +1,250 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/logging"
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+)
+
+type key int
+
+const nonceKey key = iota
+
+// NonceSource is replaced with the nonce of the request in CSP directives.
+const NonceSource = "'nonce'"
+
+const maxReportSize = 64 * 1024
+
+type Policy struct {
+	// HSTSMaxAge enables Strict-Transport-Security for https requests in
+	// production
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+
+	FrameOptions   string
+	NoSniff        bool
+	ReferrerPolicy string
+
+	// CSP maps directives to sources, NonceSource adds nonce of the request
+	CSP map[string][]string
+	// ReportOnly only reports CSP violations instead of blocking
+	ReportOnly bool
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		HSTSMaxAge:     365 * 24 * time.Hour,
+		FrameOptions:   "DENY",
+		NoSniff:        true,
+		ReferrerPolicy: "strict-origin-when-cross-origin",
+		CSP: map[string][]string{
+			"default-src":     {"'self'"},
+			"script-src":      {"'self'", NonceSource},
+			"style-src":       {"'self'", NonceSource},
+			"img-src":         {"'self'", "data:"},
+			"object-src":      {"'none'"},
+			"base-uri":        {"'self'"},
+			"frame-ancestors": {"'none'"},
+		},
+	}
+}
+
+type Security struct {
+	isProd       bool
+	policy       Policy
+	reportPrefix string
+
+	logging *logging.Logging
+}
+
+// New creates security headers middleware. CSP violations are reported to
+// reportPrefix, if it is not empty. HSTS is only sent in production, so
+// browsers do not pin https for development hosts.
+func New(isProd bool, policy Policy, reportPrefix string) *Security {
+	return &Security{
+		isProd:       isProd,
+		policy:       policy,
+		reportPrefix: reportPrefix,
+	}
+}
+
+func (s *Security) Dependencies() []string {
+	return []string{"Logging", "Render"}
+}
+
+func (s *Security) Configure(app *gongo.App) error {
+	if err := app.Lookup("Logging", &s.logging); err != nil {
+		return err
+	}
+	var r *render.Render
+	if err := app.Lookup("Render", &r); err != nil {
+		return err
+	}
+
+	r.AddContextFunc(func(req *http.Request, ctx render.Context) {
+		ctx["csp_nonce"] = Nonce(req)
+	})
+
+	return nil
+}
+
+// Nonce returns CSP nonce of the request, to be used in nonce attribute of
+// inline scripts and styles.
+func Nonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(nonceKey).(string)
+	return nonce
+}
+
+func (s *Security) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+
+		if s.isProd && s.policy.HSTSMaxAge > 0 && (r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https") {
+			hsts := fmt.Sprintf("max-age=%d", int(s.policy.HSTSMaxAge.Seconds()))
+			if s.policy.HSTSIncludeSubdomains {
+				hsts += "; includeSubDomains"
+			}
+			if s.policy.HSTSPreload {
+				hsts += "; preload"
+			}
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		if s.policy.FrameOptions != "" {
+			header.Set("X-Frame-Options", s.policy.FrameOptions)
+		}
+		if s.policy.NoSniff {
+			header.Set("X-Content-Type-Options", "nosniff")
+		}
+		if s.policy.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", s.policy.ReferrerPolicy)
+		}
+
+		if len(s.policy.CSP) > 0 {
+			nonce, err := newNonce()
+			if err != nil {
+				s.logging.WithRequest(r).WithError(err).Error("could not generate csp nonce")
+			}
+			r = r.WithContext(context.WithValue(r.Context(), nonceKey, nonce))
+
+			name := "Content-Security-Policy"
+			if s.policy.ReportOnly {
+				name = "Content-Security-Policy-Report-Only"
+			}
+			header.Set(name, s.csp(nonce))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Security) csp(nonce string) string {
+	directives := make([]string, 0, len(s.policy.CSP))
+	for name := range s.policy.CSP {
+		directives = append(directives, name)
+	}
+	sort.Strings(directives)
+
+	parts := make([]string, 0, len(directives)+1)
+	for _, name := range directives {
+		sources := make([]string, 0, len(s.policy.CSP[name]))
+		for _, source := range s.policy.CSP[name] {
+			if source == NonceSource {
+				if nonce == "" {
+					continue
+				}
+				source = "'nonce-" + nonce + "'"
+			}
+			sources = append(sources, source)
+		}
+		parts = append(parts, strings.TrimSpace(name+" "+strings.Join(sources, " ")))
+	}
+
+	if s.reportPrefix != "" {
+		parts = append(parts, "report-uri "+gongo.PathPrefix(s.reportPrefix)+"/")
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// CacheSkip implements cache.Skipper, pages with the nonce of the request
+// would break CSP of other requests.
+func (s *Security) CacheSkip(r *http.Request, body []byte) bool {
+	nonce := Nonce(r)
+	return nonce != "" && bytes.Contains(body, []byte(nonce))
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func (s *Security) Prefix() string {
+	return s.reportPrefix
+}
+
+// CSRFExempt excludes violation reports from csrf verification, browsers
+// send them without a token.
+func (s *Security) CSRFExempt() []string {
+	if s.reportPrefix == "" {
+		return nil
+	}
+	return []string{gongo.PathPrefix(s.reportPrefix) + "/"}
+}
+
+// ServeMux receives CSP violation reports and logs them.
+func (s *Security) ServeMux() http.Handler {
+	router := chi.NewRouter()
+
+	router.Post("/", func(w http.ResponseWriter, r *http.Request) {
+		report, err := readReport(r.Body)
+		if err != nil {
+			s.logging.WithRequest(r).WithError(err).Warn("invalid csp report")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.logging.WithRequest(r).WithField("Report", report).Warn("csp violation")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return router
+}
+
+func readReport(body io.Reader) (interface{}, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxReportSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read report")
+	}
+
+	// report-uri sends {"csp-report": {...}}, Reporting API sends a list
+	var report interface{}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, errors.Wrap(err, "could not decode report")
+	}
+	if m, ok := report.(map[string]interface{}); ok {
+		if cspReport, ok := m["csp-report"]; ok {
+			return cspReport, nil
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,171 @@
+package security
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matematik7/gongo/internal/apptest"
+	"github.com/matematik7/gongo/render"
+	"github.com/matematik7/gongo/server"
+)
+
+func newSecurity(t *testing.T, isProd bool, policy Policy, reportPrefix string) (*Security, *server.Server) {
+	r := render.New(false)
+	r.AddTemplates(http.FS(fstest.MapFS{
+		"page.html": {Data: []byte(`<script nonce="{{ csp_nonce }}"></script>`)},
+	}))
+
+	s := New(isProd, policy, reportPrefix)
+	srv := server.New("127.0.0.1:0")
+
+	app := apptest.New(r)
+	app.Register("Security", s)
+	app.Register("Server", srv)
+	if err := app.Configure(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Router().Get("/page", func(w http.ResponseWriter, req *http.Request) {
+		r.Template(w, req, "page.html", render.Context{})
+	})
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		srv.Stop(context.Background())
+	})
+
+	return s, srv
+}
+
+func serve(srv *server.Server, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	return w
+}
+
+func TestNonce(t *testing.T) {
+	_, srv := newSecurity(t, false, DefaultPolicy(), "")
+
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		w := serve(srv, httptest.NewRequest("GET", "/page", nil))
+
+		csp := w.Header().Get("Content-Security-Policy")
+		start := strings.Index(csp, "'nonce-")
+		if start == -1 {
+			t.Fatalf("no nonce in %s", csp)
+		}
+		nonce := csp[start+len("'nonce-"):]
+		nonce = nonce[:strings.Index(nonce, "'")]
+
+		if seen[nonce] {
+			t.Errorf("nonce %s is reused", nonce)
+		}
+		seen[nonce] = true
+
+		if body := w.Body.String(); body != `<script nonce="`+nonce+`"></script>` {
+			t.Errorf("template got %s, header nonce %s", body, nonce)
+		}
+		if strings.Contains(csp, "report-uri") {
+			t.Errorf("report-uri without report prefix: %s", csp)
+		}
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.ReportOnly = true
+	_, srv := newSecurity(t, false, policy, "/csp")
+
+	w := serve(srv, httptest.NewRequest("GET", "/page", nil))
+
+	want := map[string]string{
+		"X-Frame-Options":        "DENY",
+		"X-Content-Type-Options": "nosniff",
+		"Referrer-Policy":        "strict-origin-when-cross-origin",
+	}
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("%s: got %q, want %q", name, got, value)
+		}
+	}
+
+	if csp := w.Header().Get("Content-Security-Policy"); csp != "" {
+		t.Errorf("report only mode sent enforced policy %s", csp)
+	}
+	csp := w.Header().Get("Content-Security-Policy-Report-Only")
+	if !strings.Contains(csp, "default-src 'self'") || !strings.HasSuffix(csp, "; report-uri /csp/") {
+		t.Errorf("got Content-Security-Policy-Report-Only %s", csp)
+	}
+}
+
+func TestHSTS(t *testing.T) {
+	tests := []struct {
+		name   string
+		isProd bool
+		https  bool
+		want   string
+	}{
+		{"prod https", true, true, "max-age=31536000"},
+		{"prod http", true, false, ""},
+		{"dev https", false, true, ""},
+	}
+	for _, test := range tests {
+		_, srv := newSecurity(t, test.isProd, DefaultPolicy(), "")
+
+		req := httptest.NewRequest("GET", "/page", nil)
+		if test.https {
+			req.Header.Set("X-Forwarded-Proto", "https")
+		}
+		if got := serve(srv, req).Header().Get("Strict-Transport-Security"); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestReports(t *testing.T) {
+	_, srv := newSecurity(t, false, DefaultPolicy(), "/csp")
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"csp-report": {"violated-directive": "script-src"}}`, http.StatusNoContent},
+		{`[{"type": "csp-violation"}]`, http.StatusNoContent},
+		{`not json`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		w := serve(srv, httptest.NewRequest("POST", "/csp/", strings.NewReader(test.body)))
+		if w.Code != test.status {
+			t.Errorf("%s: got status %d, want %d", test.body, w.Code, test.status)
+		}
+	}
+}
+
+func TestReportsDisabled(t *testing.T) {
+	_, srv := newSecurity(t, false, DefaultPolicy(), "")
+
+	w := serve(srv, httptest.NewRequest("POST", "/csp/", strings.NewReader(`{}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestCacheSkip(t *testing.T) {
+	s, _ := newSecurity(t, false, DefaultPolicy(), "")
+
+	var skip, skipUnused bool
+	s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		skip = s.CacheSkip(r, []byte(`<script nonce="`+Nonce(r)+`">`))
+		skipUnused = s.CacheSkip(r, []byte(`<script src="/app.js">`))
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if !skip || skipUnused {
+		t.Errorf("got skip %v with nonce and %v without", skip, skipUnused)
+	}
+}
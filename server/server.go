@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/matematik7/gongo"
+	"github.com/matematik7/gongo/logging"
+	"github.com/matematik7/gongo/render"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type Server struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	addr string
+
+	log    *logrus.Logger
+	router chi.Router
+	server *http.Server
+	mounts []*mount
+}
+
+// mount serves routes of a mounter, its handler is set on start, because
+// handlers are only available after every component is configured.
+type mount struct {
+	mounter gongo.Mounter
+	handler http.Handler
+}
+
+func (m *mount) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.handler == nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	m.handler.ServeHTTP(w, r)
+}
+
+func New(addr string) *Server {
+	return &Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+
+		addr: addr,
+	}
+}
+
+func (s *Server) Dependencies() []string {
+	return []string{"Logging", "Render"}
+}
+
+func (s *Server) Configure(app *gongo.App) error {
+	var l *logging.Logging
+	if err := app.Lookup("Logging", &l); err != nil {
+		return err
+	}
+	s.log = l.Logger()
+	var r *render.Render
+	if err := app.Lookup("Render", &r); err != nil {
+		return err
+	}
+
+	s.router = chi.NewRouter()
+	s.router.Use(middleware.RequestID)
+	s.router.Use(middleware.RealIP)
+
+	// middleware is chained in dependency order, so components wrap the
+	// requests after components they depend on, for example Render
+	// recovers panics inside of the Logging access log
+	order, err := app.Order()
+	if err != nil {
+		return errors.Wrap(err, "could not determine middleware order")
+	}
+	for _, name := range order {
+		component := app.Get(name)
+		if middlewarer, ok := component.(gongo.Middlewarer); ok {
+			s.router.Use(middlewarer.Middleware)
+		}
+	}
+
+	// components are mounted before app routes are added to the router,
+	// so conflicting routes are reported here instead of on start
+	for _, name := range order {
+		mounter, ok := app.Get(name).(gongo.Mounter)
+		// components can have optional routes, disabled by empty prefix
+		if !ok || mounter.Prefix() == "" {
+			continue
+		}
+
+		prefix := gongo.PathPrefix(mounter.Prefix())
+		if prefix == "" || prefix == "/" {
+			return errors.Errorf("%s must be mounted under a path, got %s", name, mounter.Prefix())
+		}
+
+		m := &mount{mounter: mounter}
+		if err := mountRouter(s.router, prefix, m); err != nil {
+			return errors.Wrapf(err, "could not mount %s", name)
+		}
+		s.mounts = append(s.mounts, m)
+	}
+
+	s.router.NotFound(r.NotFound)
+	s.router.MethodNotAllowed(r.MethodNotAllowed)
+
+	s.server = &http.Server{
+		Addr:              s.addr,
+		Handler:           s.router,
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+		ReadTimeout:       s.ReadTimeout,
+		WriteTimeout:      s.WriteTimeout,
+		IdleTimeout:       s.IdleTimeout,
+	}
+
+	return nil
+}
+
+// Router returns the root router, app routes can be added to it after
+// the app is configured. Middleware is added by Middlewarer components,
+// because chi does not allow it after the components are mounted.
+func (s *Server) Router() chi.Router {
+	return s.router
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	for _, m := range s.mounts {
+		m.handler = m.mounter.ServeMux()
+	}
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return errors.Wrapf(err, "could not listen on %s", s.addr)
+	}
+
+	s.log.Infof("Listening on %s", listener.Addr())
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.log.WithError(err).Error("server failed")
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		return errors.Wrap(err, "could not shutdown server")
+	}
+
+	return nil
+}
+
+// mountRouter mounts handler at prefix, reporting conflicting routes as
+// errors instead of chi panics.
+func mountRouter(router chi.Router, prefix string, handler http.Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("%v", r)
+		}
+	}()
+
+	router.Mount(prefix, handler)
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matematik7/gongo/internal/apptest"
+)
+
+type mounter struct {
+	prefix string
+	body   string
+}
+
+func (m mounter) Prefix() string {
+	return m.prefix
+}
+
+func (m mounter) ServeMux() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(m.body))
+	})
+}
+
+func TestMount(t *testing.T) {
+	app := apptest.New(nil)
+	app.Register("Disabled", mounter{prefix: "", body: "disabled"})
+	app.Register("URL", mounter{prefix: "https://example.com/url/", body: "url"})
+	app.Register("Path", mounter{prefix: "/path", body: "path"})
+
+	s := New("127.0.0.1:0")
+	app.Register("Server", s)
+
+	if err := app.Configure(); err != nil {
+		t.Fatal(err)
+	}
+	s.Router().Get("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("page"))
+	})
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop(context.Background())
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/url", http.StatusOK, "url"},
+		{"/path/nested", http.StatusOK, "path"},
+		{"/page", http.StatusOK, "page"},
+		{"/", http.StatusNotFound, ""},
+		{"/other", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.status || (test.body != "" && w.Body.String() != test.body) {
+			t.Errorf("GET %s: got %d %q, want %d %q", test.path, w.Code, w.Body.String(), test.status, test.body)
+		}
+	}
+}
+
+func TestMountErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		mounters map[string]mounter
+		want     string
+	}{
+		{"root url", map[string]mounter{"Root": {prefix: "https://example.com"}}, "Root must be mounted under a path, got https://example.com"},
+		{"root path", map[string]mounter{"Root": {prefix: "/"}}, "Root must be mounted under a path, got /"},
+		{"conflict", map[string]mounter{"A": {prefix: "/a"}, "B": {prefix: "https://example.com/a"}}, "could not mount"},
+	}
+	for _, test := range tests {
+		app := apptest.New(nil)
+		for name, m := range test.mounters {
+			app.Register(name, m)
+		}
+		app.Register("Server", New("127.0.0.1:0"))
+
+		if err := app.Configure(); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got %v, want %s", test.name, err, test.want)
+		}
+	}
+}
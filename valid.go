@@ -0,0 +1,17 @@
+package gongo
+
+import (
+	"reflect"
+	"strings"
+)
+
+// IsRequired reports whether field has the required validator in its
+// govalidator valid tag, with or without a custom message.
+func IsRequired(field reflect.StructField) bool {
+	for _, option := range strings.Split(field.Tag.Get("valid"), ",") {
+		if strings.SplitN(option, "~", 2)[0] == "required" {
+			return true
+		}
+	}
+	return false
+}
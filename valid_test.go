@@ -0,0 +1,30 @@
+package gongo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsRequired(t *testing.T) {
+	type form struct {
+		Plain    string
+		Required string `valid:"required"`
+		Message  string `valid:"email,required~Email is required"`
+		Other    string `valid:"email,requiredx"`
+	}
+
+	want := map[string]bool{
+		"Plain":    false,
+		"Required": true,
+		"Message":  true,
+		"Other":    false,
+	}
+
+	typ := reflect.TypeOf(form{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if got := IsRequired(field); got != want[field.Name] {
+			t.Errorf("%s: got %v, want %v", field.Name, got, want[field.Name])
+		}
+	}
+}